@@ -3,65 +3,249 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
 var ServerURL string
 
+// API token (created with POST /tokens on the server) sent as bearer token
+var APIToken string
+
 // set ServerURL
 func SetServerURL(url string) {
 	ServerURL = url
 	log.Printf("Server URL set to: %s", ServerURL)
 }
 
-// send job data to backend API
-func sendJobToAPI(job Job) {
-	jsonData, err := json.Marshal(job)
+// set APIToken
+func SetAPIToken(token string) {
+	APIToken = token
+}
+
+// POST /jobs, a job that already exists (409) counts as saved
+func sendJobToAPI(job Job) error {
+	resp, err := apiRequest(http.MethodPost, "/jobs", job)
 	if err != nil {
-		log.Println("Error marshailing job data:", err)
-		return
+		return err
 	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
 
-	// Create HTTP client with timeout
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	//handle response
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		log.Printf("✓ Job saved successfully: %s at %s", job.Title, job.Company)
+	case http.StatusConflict:
+		log.Printf("⚠ Job already exists: %s at %s", job.Title, job.Company)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return tokenError(resp.StatusCode, "jobs:write")
+	default:
+		return &statusError{resp.StatusCode, "saving job: " + resp.Status}
 	}
+	return nil
+}
 
-	//create request
-	url := fmt.Sprintf("%s/jobs", ServerURL)
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Println("Error creating req:", err)
+// statusError is a response the server refused a request with
+type statusError struct {
+	status int
+	msg    string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+// permanent reports whether sending the same request again cannot succeed: any 4xx but
+// 408 Request Timeout and 429 Too Many Requests, e.g. a revoked token or a job the server finds invalid
+func permanent(err error) bool {
+	var statusErr *statusError
+	if !errors.As(err, &statusErr) {
+		return false
 	}
+	status := statusErr.status
+	return status >= 400 && status < 500 && status != http.StatusRequestTimeout && status != http.StatusTooManyRequests
+}
 
-	//Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "JobEmailWatcher/1.0")
+// explains a 401 or 403, a request needing scope will fail the same way until the token is replaced
+func tokenError(status int, scope string) error {
+	if status == http.StatusForbidden {
+		return &statusError{status, fmt.Sprintf("API token needs the %s scope, create one with \"scopes\": [\"jobs:read\", \"jobs:write\"]", scope)}
+	}
+	return &statusError{status, "API token rejected (missing, revoked or invalid)"}
+}
 
-	//make request
-	resp, err := client.Do(req)
+// GET /statuses, the job statuses the server accepts
+func fetchStatuses() ([]string, error) {
+	resp, err := apiRequest(http.MethodGet, "/statuses", nil)
 	if err != nil {
-		log.Println("Error sending job to API:", err)
-		return
+		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{resp.StatusCode, "loading statuses: " + resp.Status}
+	}
 
-	//handle response
+	var body struct {
+		Statuses []string `json:"statuses"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("loading statuses: %w", err)
+	}
+	if len(body.Statuses) == 0 {
+		return nil, errors.New("loading statuses: the server sent none")
+	}
+	return body.Statuses, nil
+}
+
+// JobMatch is a job on the server that a follow-up email may be about, see GET /jobs/match
+type JobMatch struct {
+	ID uint `json:"id"`
+	Job
+	Score float64 `json:"score"` // 0 to 1
+}
+
+// matches below this are different applications, e.g. another role at the same company
+const minMatchScore = 0.7
+
+// saveJob updates the job a follow-up email (interview, offer, rejection) is about, or creates a new job
+func saveJob(job Job) error {
+	if isFollowUp(job) {
+		updated, err := updateMatchingJob(job)
+		if err != nil || updated {
+			return err
+		}
+	}
+	return sendJobToAPI(job)
+}
+
+// interview, offer and rejection emails are about a job applied to before
+func isFollowUp(job Job) bool {
+	return job.Status != "" && job.Status != "applied"
+}
+
+// moves the best matching job to the status of the follow-up and adds a note about the email.
+// Returns false when no job matches well enough
+func updateMatchingJob(followUp Job) (bool, error) {
+	match, err := findMatchingJob(followUp.Company, followUp.Title)
+	if err != nil || match == nil {
+		return false, err
+	}
+
+	note := followUpNote(followUp)
+	if followUp.SourceMessageID != "" && strings.Contains(match.Notes, followUp.SourceMessageID) {
+		log.Printf("⚠ Follow-up already applied to job %d: %s at %s", match.ID, match.Title, match.Company)
+		return true, nil
+	}
+	notes := note
+	if match.Notes != "" {
+		notes = match.Notes + "\n" + note
+	}
+
+	status, err := updateJob(match.ID, map[string]string{"status": followUp.Status, "notes": notes})
+	if err != nil {
+		return false, err
+	}
+	noteOnly := status == http.StatusUnprocessableEntity
+	if noteOnly {
+		// e.g. an interview email for a job already rejected, keep its status and only add the note
+		log.Printf("⚠ Job %d is %s, not moving it to %s", match.ID, match.Status, followUp.Status)
+		if status, err = updateJob(match.ID, map[string]string{"notes": notes}); err != nil {
+			return false, err
+		}
+	}
+	if status != http.StatusOK {
+		return false, &statusError{status, fmt.Sprintf("updating job %d: %s", match.ID, http.StatusText(status))}
+	}
+
+	if noteOnly {
+		log.Printf("✓ Note added, status left at %s: %s at %s", match.Status, match.Title, match.Company)
+	} else {
+		log.Printf("✓ Job updated to %s: %s at %s", followUp.Status, match.Title, match.Company)
+	}
+	return true, nil
+}
+
+// "2025-10-20 rejected: Update on your application (<id@acme.com>)"
+func followUpNote(job Job) string {
+	note := fmt.Sprintf("%s %s: %s", job.AppliedDate, job.Status, job.Notes)
+	if job.SourceMessageID != "" {
+		note += " (" + job.SourceMessageID + ")"
+	}
+	return note
+}
+
+// best match of GET /jobs/match, nil if none scores at least minMatchScore
+func findMatchingJob(company, title string) (*JobMatch, error) {
+	if company == "" || company == "Unknown Company" {
+		return nil, nil
+	}
+	query := url.Values{"company": {company}, "limit": {"1"}}
+	if title != "" && title != "Unknown Position" {
+		query.Set("title", title)
+	}
+
+	resp, err := apiRequest(http.MethodGet, "/jobs/match?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 	switch resp.StatusCode {
 	case http.StatusOK:
-		log.Printf("✓ Job saved successfully: %s at %s", job.Title, job.Company)
-	case http.StatusCreated:
-		log.Printf("✓ Job saved successfully: %s at %s", job.Title, job.Company)
-	case http.StatusConflict:
-		log.Printf("⚠ Job already exists: %s at %s", job.Title, job.Company)
-	case http.StatusBadRequest:
-		log.Printf("✗ Bad request for job: %s at %s", job.Title, job.Company)
-	case http.StatusInternalServerError:
-		log.Printf("✗ Server error when saving job: %s at %s", job.Title, job.Company)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, tokenError(resp.StatusCode, "jobs:read")
 	default:
-		log.Printf("✗ Failed to save job: %s (status: %s)", job.Title, resp.Status)
+		return nil, &statusError{resp.StatusCode, "matching jobs: " + resp.Status}
+	}
+
+	var matches []JobMatch
+	if err := json.NewDecoder(resp.Body).Decode(&matches); err != nil {
+		return nil, fmt.Errorf("matching jobs: %w", err)
+	}
+	if len(matches) == 0 || matches[0].Score < minMatchScore {
+		return nil, nil
 	}
+	return &matches[0], nil
+}
+
+// PUT /jobs/:id with the fields to change, returns the response status
+func updateJob(id uint, fields map[string]string) (int, error) {
+	resp, err := apiRequest(http.MethodPut, fmt.Sprintf("/jobs/%d", id), fields)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// request to the server API with the API token, body is sent as JSON
+func apiRequest(method, path string, body any) (*http.Response, error) {
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, ServerURL+path, payload)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", "JobEmailWatcher/1.0")
+	req.Header.Set("Authorization", "Bearer "+APIToken)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	return client.Do(req)
 }
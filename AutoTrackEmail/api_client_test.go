@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeJobAPI serves /jobs like the server, GET /jobs/match returns matches as they are
+type fakeJobAPI struct {
+	mu         sync.Mutex
+	matches    []JobMatch
+	rejectPuts bool // answer PUTs that change the status with 422
+	requests   []string
+	puts       []map[string]string
+	reviews    []pendingJob // bodies of POST /reviews
+	jobStatus  int          // answer to POST /jobs, 201 when not set
+	matchError int          // answer to GET /jobs/match instead of the matches
+	statuses   []string     // answer to GET /statuses
+}
+
+func startFakeJobAPI(t *testing.T, matches ...JobMatch) *fakeJobAPI {
+	t.Helper()
+	api := &fakeJobAPI{matches: matches}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /jobs/match", func(w http.ResponseWriter, r *http.Request) {
+		api.record(r)
+		api.mu.Lock()
+		status := api.matchError
+		api.mu.Unlock()
+		if status != 0 {
+			http.Error(w, "Token is missing scope jobs:read", status)
+			return
+		}
+		if r.URL.Query().Get("company") == "" {
+			http.Error(w, "company is required", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(api.matches)
+	})
+	mux.HandleFunc("GET /statuses", func(w http.ResponseWriter, r *http.Request) {
+		api.record(r)
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"statuses": api.statuses})
+	})
+	mux.HandleFunc("PUT /jobs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		api.record(r)
+		var fields map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		api.mu.Lock()
+		api.puts = append(api.puts, fields)
+		reject := api.rejectPuts && fields["status"] != ""
+		api.mu.Unlock()
+		if reject {
+			http.Error(w, "cannot change status", http.StatusUnprocessableEntity)
+			return
+		}
+		w.Write([]byte("{}"))
+	})
+	mux.HandleFunc("POST /reviews", func(w http.ResponseWriter, r *http.Request) {
+		api.record(r)
+		var pending pendingJob
+		if err := json.NewDecoder(r.Body).Decode(&pending); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		api.mu.Lock()
+		api.reviews = append(api.reviews, pending)
+		api.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("POST /jobs", func(w http.ResponseWriter, r *http.Request) {
+		api.record(r)
+		io.Copy(io.Discard, r.Body)
+		api.mu.Lock()
+		status := api.jobStatus
+		api.mu.Unlock()
+		if status == 0 {
+			status = http.StatusCreated
+		}
+		w.WriteHeader(status)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	previous := ServerURL
+	ServerURL = server.URL
+	t.Cleanup(func() { ServerURL = previous })
+	return api
+}
+
+func (api *fakeJobAPI) record(r *http.Request) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.requests = append(api.requests, r.Method+" "+r.URL.Path)
+}
+
+func (api *fakeJobAPI) requestLog() string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return strings.Join(api.requests, ", ")
+}
+
+var rejection = Job{
+	Company:         "Acme",
+	Title:           "Backend Developer",
+	Status:          "rejected",
+	AppliedDate:     "2025-10-20",
+	Notes:           "Update on your application",
+	SourceMessageID: "<42@acme.example>",
+}
+
+func TestSaveJobUpdatesMatchingJob(t *testing.T) {
+	api := startFakeJobAPI(t, JobMatch{ID: 7, Job: Job{Company: "Acme Inc.", Title: "Backend Developer", Status: "interview", Notes: "Your application was sent to Acme"}, Score: 1})
+
+	saveJob(rejection)
+
+	if got, want := api.requestLog(), "GET /jobs/match, PUT /jobs/7"; got != want {
+		t.Fatalf("requests = %s, want %s", got, want)
+	}
+	want := map[string]string{
+		"status": "rejected",
+		"notes":  "Your application was sent to Acme\n2025-10-20 rejected: Update on your application (<42@acme.example>)",
+	}
+	if got := api.puts[0]; got["status"] != want["status"] || got["notes"] != want["notes"] {
+		t.Errorf("PUT body = %q, want %q", got, want)
+	}
+}
+
+func TestSaveJobCreatesJobWithoutGoodMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		job     Job
+		matches []JobMatch
+		want    string
+	}{
+		{"no match", rejection, nil, "GET /jobs/match, POST /jobs"},
+		{"weak match", rejection, []JobMatch{{ID: 3, Job: Job{Company: "Acme", Title: "Designer"}, Score: 0.5}}, "GET /jobs/match, POST /jobs"},
+		{"unknown company", Job{Company: "Unknown Company", Status: "interview"}, nil, "POST /jobs"},
+		{"new application", Job{Company: "Acme", Status: "applied"}, []JobMatch{{ID: 7, Score: 1}}, "POST /jobs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := startFakeJobAPI(t, tt.matches...)
+			saveJob(tt.job)
+			if got := api.requestLog(); got != tt.want {
+				t.Errorf("requests = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveJobKeepsStatusTheServerRefuses(t *testing.T) {
+	api := startFakeJobAPI(t, JobMatch{ID: 7, Job: Job{Company: "Acme", Status: "rejected"}, Score: 0.9})
+	api.rejectPuts = true
+	var logged strings.Builder
+	log.SetOutput(&logged)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	saveJob(Job{Company: "Acme", Status: "interview", AppliedDate: "2025-10-21", Notes: "Interview invitation"})
+
+	if got, want := api.requestLog(), "GET /jobs/match, PUT /jobs/7, PUT /jobs/7"; got != want {
+		t.Fatalf("requests = %s, want %s", got, want)
+	}
+	// the second update only adds the note
+	if fields := api.puts[1]; fields["status"] != "" || fields["notes"] != "2025-10-21 interview: Interview invitation" {
+		t.Errorf("second PUT body = %q", fields)
+	}
+	if !strings.Contains(logged.String(), "Note added, status left at rejected") || strings.Contains(logged.String(), "updated to interview") {
+		t.Errorf("log = %s", logged.String())
+	}
+}
+
+func TestSaveJobSkipsFollowUpAlreadyApplied(t *testing.T) {
+	api := startFakeJobAPI(t, JobMatch{ID: 7, Job: Job{Company: "Acme", Status: "rejected", Notes: "2025-10-20 rejected: Update on your application (<42@acme.example>)"}, Score: 1})
+
+	saveJob(rejection)
+
+	if got, want := api.requestLog(), "GET /jobs/match"; got != want {
+		t.Errorf("requests = %s, want %s", got, want)
+	}
+}
+
+func TestSaveJobNeedsReadScopeForFollowUps(t *testing.T) {
+	api := startFakeJobAPI(t)
+	api.matchError = http.StatusForbidden
+
+	err := saveJob(rejection)
+	if err == nil || !strings.Contains(err.Error(), "jobs:read") {
+		t.Errorf("saveJob() = %v, want an error naming the jobs:read scope", err)
+	}
+	// creating the job instead would add a duplicate of the one the email is about
+	if got, want := api.requestLog(), "GET /jobs/match"; got != want {
+		t.Errorf("requests = %s, want %s", got, want)
+	}
+}
+
+func TestJobStatusesComeFromTheServer(t *testing.T) {
+	api := startFakeJobAPI(t)
+	api.statuses = []string{"applied", "interview", "offer", "rejected", "ghosted"}
+	previous := JobStatuses
+	t.Cleanup(func() { SetJobStatuses(previous) })
+
+	statuses, err := fetchStatuses()
+	if err != nil || !slices.Equal(statuses, api.statuses) {
+		t.Fatalf("fetchStatuses() = %v, %v", statuses, err)
+	}
+	SetJobStatuses(statuses)
+	if got := normalizeStatus("Ghosted"); got != "ghosted" {
+		t.Errorf("normalizeStatus(Ghosted) = %q, want the server's ghosted", got)
+	}
+
+	// a status the server dropped is never sent
+	SetJobStatuses([]string{"applied", "interview", "rejected"})
+	if got := normalizeStatus("offer"); got != "" {
+		t.Errorf("normalizeStatus(offer) = %q, want none", got)
+	}
+	if status, _, _ := statusFromSubject("Congratulations, an offer from Acme"); status != "applied" {
+		t.Errorf("status from an offer subject = %q, want the default", status)
+	}
+
+	api.statuses = nil
+	if _, err := fetchStatuses(); err == nil {
+		t.Error("fetchStatuses() accepted an empty list")
+	}
+}
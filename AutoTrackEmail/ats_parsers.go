@@ -0,0 +1,217 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// dedicated parsers, registered in this order before the generic fallback
+var atsParsers = []Parser{
+	linkedInParser{linkedIn},
+	greenhouse,
+	lever,
+	workday,
+	icims,
+	smartRecruiters,
+	ashby,
+	indeed,
+}
+
+func patterns(exprs ...string) []*regexp.Regexp {
+	res := make([]*regexp.Regexp, len(exprs))
+	for i, expr := range exprs {
+		res[i] = regexp.MustCompile(expr)
+	}
+	return res
+}
+
+var linkedIn = &atsParser{
+	name:    "linkedin",
+	domains: []string{"linkedin.com"},
+	subject: patterns(
+		`(?i)your application was sent to\s+(?P<company>.+?)(?:\s+(?:-|via)\s+LinkedIn)?(?:\s*\||\s*$)`,
+		`(?i)application sent.*?\bat\s+(?P<company>.+?)(?:\s*\||\s*$)`,
+		`(?i)your application was viewed by\s+(?P<company>.+?)\s*$`,
+		`(?i)your application to\s+(?P<title>.+?)\s+at\s+(?P<company>.+?)\s*$`,
+	),
+}
+
+// LinkedIn puts the title on its own line under "Your application was sent to <company>"
+type linkedInParser struct {
+	*atsParser
+}
+
+func (p linkedInParser) Parse(email EmailData) *Job {
+	job := p.atsParser.Parse(email)
+	title := linkedInTitleFromBody(email.Body)
+	if title == "" {
+		return job
+	}
+	if job == nil {
+		status, statusConfidence, statusSource := statusFromSubject(email.Subject)
+		job = &Job{
+			Status:          status,
+			AppliedDate:     email.Date,
+			Notes:           email.Subject,
+			SourceMessageID: email.MessageID,
+			Confidence:      Confidence{Status: statusConfidence},
+			Sources:         Sources{Status: parserSource(p.name, statusSource)},
+		}
+	}
+	if job.Title == "" {
+		job.Title, job.Confidence.Title = title, 0.8 // the line under the company, usually the title
+		job.Sources.Title = p.name + " body line under the company"
+	}
+	return job
+}
+
+func linkedInTitleFromBody(body string) string {
+	foundCompanyLine := false
+	for _, line := range strings.Split(body, "\n") {
+		// Remove markdown formatting
+		line = strings.TrimSpace(strings.ReplaceAll(line, "*", ""))
+
+		if strings.Contains(strings.ToLower(line), "your application was sent to") {
+			foundCompanyLine = true
+			continue
+		}
+		if !foundCompanyLine || line == "" {
+			continue
+		}
+
+		// "Title · Company · Location" or "Title - Company"
+		title, _, _ := strings.Cut(line, "·")
+		title = strings.TrimSpace(title)
+		if before, after, ok := strings.Cut(title, " - "); ok && !strings.Contains(strings.ToLower(after), "developer") {
+			title = strings.TrimSpace(before)
+		}
+
+		if len(title) > 1 && len(title) < 100 && !isLocationOrDate(title) {
+			return title
+		}
+	}
+	return ""
+}
+
+var greenhouse = &atsParser{
+	name:      "greenhouse",
+	domains:   []string{"greenhouse.io", "greenhouse-mail.io"},
+	bodyHints: []string{"boards.greenhouse.io", "job-boards.greenhouse.io"},
+	subject: patterns(
+		`(?i)thank you for (?:applying|your application) to\s+(?P<company>.+?)\s*!?$`,
+		`(?i)your application (?:to|with)\s+(?P<company>.+?)\s*$`,
+	),
+	body: patterns(
+		`(?i)thank you for your interest in\s+(?P<company>[^!.\n]+)`,
+		`(?i)received your application for (?:the\s+)?(?P<title>[^,.\n]+?)(?:\s+(?:position|role))?(?:,|\.|\s+and\b|\s+at\b|\n|$)`,
+	),
+}
+
+var lever = &atsParser{
+	name:      "lever",
+	domains:   []string{"lever.co"},
+	bodyHints: []string{"jobs.lever.co"},
+	subject: patterns(
+		`(?i)thank you for your application to\s+(?P<company>.+?)\s*$`,
+		`(?i)thanks? (?:you )?for applying to\s+(?P<company>.+?)\s*!?$`,
+		`(?i)^(?P<company>.+?)\s+[-|]\s+application received`,
+	),
+	body: patterns(
+		`(?i)thanks? (?:you )?for applying to\s+(?P<company>[^!.\n]+)`,
+		`(?i)(?:for|to) the\s+(?P<title>[^\n.,]+?)\s+(?:position|role|opening)`,
+	),
+}
+
+var workday = &atsParser{
+	name:      "workday",
+	domains:   []string{"myworkday.com", "workday.com"},
+	bodyHints: []string{"myworkdayjobs.com"},
+	subject: patterns(
+		`(?i)thank you for applying (?:to|for)\s+(?:the\s+)?(?P<title>.+?)\s+(?:position\s+)?at\s+(?P<company>.+?)\s*$`,
+		`(?i)application received\s*[:-]\s*(?P<title>.+?)(?:\s*\(.*\))?\s*$`,
+	),
+	body: patterns(
+		`(?i)(?:applying|application) (?:to|for) (?:the\s+)?(?P<title>[^\n.,]+?)\s+(?:position|role|job)\b`,
+		`(?i)(?:position|role|job)(?:\s+\S*\d\S*)?\s+(?:at|with)\s+(?P<company>[^\n.!,]+)`,
+		`(?i)interest in (?:a career (?:at|with)|working (?:at|for)|joining)\s+(?P<company>[^\n.!,]+)`,
+	),
+}
+
+var icims = &atsParser{
+	name:      "icims",
+	domains:   []string{"icims.com"},
+	bodyHints: []string{".icims.com"},
+	subject: patterns(
+		`(?i)thank you for applying for (?:the\s+)?(?P<title>.+?)(?:\s+(?:position|role))?(?:\s+at\s+(?P<company>.+?))?\s*$`,
+		`(?i)thank you for applying to\s+(?P<company>.+?)\s*$`,
+	),
+	body: patterns(
+		`(?i)position of\s+(?P<title>[^\n.,]+?)(?:\s+at\s+(?P<company>[^\n.,!]+))?(?:[\n.,]|$)`,
+		`(?i)interest in (?:employment (?:at|with)\s+)?(?P<company>[^\n.!,]+)`,
+	),
+}
+
+var smartRecruiters = &atsParser{
+	name:      "smartrecruiters",
+	domains:   []string{"smartrecruiters.com"},
+	bodyHints: []string{"jobs.smartrecruiters.com"},
+	subject: patterns(
+		`(?i)thank you for your application to\s+(?P<company>.+?)\s*$`,
+		`(?i)your application for\s+(?P<title>.+?)(?:\s+at\s+(?P<company>.+?))?\s*$`,
+	),
+	body: patterns(
+		`(?i)applying for (?:the\s+)?(?P<title>[^\n]+?)\s+(?:position|role)\s+at\s+(?P<company>[^\n.!,]+)`,
+		`(?i)application (?:to|with)\s+(?P<company>[^\n.!,]+)`,
+	),
+}
+
+var ashby = &atsParser{
+	name:      "ashby",
+	domains:   []string{"ashbyhq.com"},
+	bodyHints: []string{"jobs.ashbyhq.com"},
+	subject: patterns(
+		`(?i)thanks? (?:you )?for applying to\s+(?P<company>.+?)\s*!?$`,
+		`(?i)your application to\s+(?P<company>.+?)\s*$`,
+	),
+	body: patterns(
+		`(?i)(?:applying|application) for (?:the\s+)?(?P<title>[^\n]+?)\s+(?:role|position)(?:\s+at\s+(?P<company>[^\n.!,]+))?`,
+	),
+}
+
+var indeed = &atsParser{
+	name:    "indeed",
+	domains: []string{"indeed.com", "indeedemail.com"},
+	subject: patterns(
+		`(?i)indeed application:\s*(?P<title>.+?)\s*$`,
+		`(?i)application submitted\s*[:-]\s*(?P<title>.+?)(?:\s+at\s+(?P<company>.+?))?\s*$`,
+	),
+	body: patterns(
+		`(?i)application submitted\s*\n\s*(?P<title>[^\n]+)\n\s*(?P<company>[^\n]+?)\s+[-–]\s`,
+		`(?i)(?:items were sent|application (?:has been |was )?sent) to\s+(?P<company>[^\n.!]+)`,
+	),
+}
+
+// Helper function to check if a string looks like location or date info
+func isLocationOrDate(text string) bool {
+	textLower := strings.ToLower(text)
+
+	// Common location indicators
+	locationWords := []string{"toronto", "vancouver", "montreal", "calgary", "ottawa", "remote", "hybrid", "on-site", "ontario", "canada", "usa", "bc", "ab", "qc"}
+	for _, word := range locationWords {
+		if strings.Contains(textLower, word) {
+			return true
+		}
+	}
+
+	// Date patterns
+	if strings.Contains(textLower, "applied on") || strings.Contains(textLower, "2024") || strings.Contains(textLower, "2025") {
+		return true
+	}
+
+	// Check if it contains only location-like patterns (City, Province format)
+	if strings.Contains(text, ",") && len(strings.Split(text, ",")) == 2 {
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Checkpoint remembers how far the mailbox has been processed.
+// UIDs only mean something together with the mailbox's UIDVALIDITY,
+// if the server changes it every UID may have been reassigned and the mailbox is scanned again
+type Checkpoint struct {
+	Mailbox     string `json:"mailbox"`
+	UIDValidity uint32 `json:"uid_validity"`
+	LastUID     uint32 `json:"last_uid"` // highest UID already processed
+	// job emails up to LastUID whose body could not be downloaded yet, fetched again next time
+	Unfetched []uint32 `json:"unfetched,omitempty"`
+}
+
+// CheckpointStore keeps the checkpoint in a JSON state file
+type CheckpointStore struct {
+	Path string
+}
+
+// Load returns an empty checkpoint (full scan) if the state file does not exist yet
+func (s *CheckpointStore) Load() (Checkpoint, error) {
+	var cp Checkpoint
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	err = json.Unmarshal(data, &cp)
+	return cp, err
+}
+
+// Save writes the checkpoint atomically so a crash never leaves a half written file
+func (s *CheckpointStore) Save(cp Checkpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.Path, data)
+}
+
+// write to a temp file in the same folder and rename it over path.
+// The file is only readable by the owner
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
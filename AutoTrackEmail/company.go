@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+	"gopkg.in/yaml.v3"
+)
+
+// CompanyResolver infers the company from who sent an email: a domain mapping file first,
+// then the sender's display name and domain. ATS and job board senders never name the
+// company themselves, for them the employer comes from the display name, the Reply-To
+// address, the ATS subdomain or tenant, or the job links in the body
+type CompanyResolver struct {
+	domains map[string]string // lower case domain -> company, subdomains match too
+}
+
+func NewCompanyResolver(domains map[string]string) *CompanyResolver {
+	r := &CompanyResolver{domains: map[string]string{}}
+	for domain, company := range domains {
+		r.domains[strings.ToLower(strings.TrimSpace(domain))] = strings.TrimSpace(company)
+	}
+	return r
+}
+
+// UseCompanyDomains replaces the company resolver of ParseJobFromEmail, e.g. with one that has a mapping file
+func UseCompanyDomains(companies *CompanyResolver) {
+	defaultParsers.ResolveCompanies(companies)
+}
+
+// LoadCompanyDomains reads a YAML or JSON object of domain: company
+func LoadCompanyDomains(path string) (*CompanyResolver, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var domains map[string]string
+	if err := yaml.Unmarshal(data, &domains); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return NewCompanyResolver(domains), nil
+}
+
+// Resolve returns the company of the sender, how sure it is and what it comes from, "" if it cannot tell.
+// A company of the mapping file has confidenceRule and beats any parser
+func (r *CompanyResolver) Resolve(email EmailData) (string, float64, string) {
+	for _, addr := range []string{email.From, email.ReplyTo, email.ReturnPath} {
+		if company, domain := r.mapped(addressDomain(addr)); company != "" {
+			return company, confidenceRule, "company domains " + domain
+		}
+	}
+
+	if isRelaySender(email.From) {
+		return relayCompany(email)
+	}
+	return employerCompany(email.FromName, email.From)
+}
+
+// the mapping of domain or of its closest parent domain, and the domain mapped
+func (r *CompanyResolver) mapped(domain string) (string, string) {
+	for domain != "" {
+		if company, ok := r.domains[domain]; ok {
+			return company, domain
+		}
+		_, domain, _ = strings.Cut(domain, ".")
+	}
+	return "", ""
+}
+
+// senders that mail on behalf of employers, besides those with a dedicated parser
+var relayDomains = []string{
+	"workablemail.com", "workable.com", "jobvite.com", "bamboohr.com", "taleo.net",
+	"successfactors.com", "breezy.hr", "recruitee.com", "teamtailor.com", "jazzhr.com",
+	"applytojob.com", "myworkdayjobs.com", "ziprecruiter.com", "glassdoor.com", "wellfound.com",
+}
+
+// domainMatcher is implemented by the ATS parsers
+type domainMatcher interface {
+	fromDomain(from string) bool
+}
+
+func isRelaySender(from string) bool {
+	for _, p := range atsParsers {
+		if m, ok := p.(domainMatcher); ok && m.fromDomain(from) {
+			return true
+		}
+	}
+	domain := addressDomain(from)
+	for _, d := range relayDomains {
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+func relayCompany(email EmailData) (string, float64, string) {
+	// "Initech <no-reply@hire.lever.co>"
+	if name := cleanDisplayName(email.FromName); name != "" && !isATSName(name) {
+		return name, 0.7, "display name"
+	}
+
+	// recruiters often reply from their own address
+	if email.ReplyTo != "" && !isRelaySender(email.ReplyTo) {
+		if company, confidence, source := employerCompany("", email.ReplyTo); company != "" {
+			return company, confidence, "Reply-To " + source
+		}
+	}
+
+	// "careers@hooli.icims.com", "globex@myworkday.com"
+	local, domain, _ := strings.Cut(strings.ToLower(email.From), "@")
+	if labels := strings.Split(domain, "."); len(labels) > 2 {
+		if tenant := labels[0]; !isGenericLabel(tenant) {
+			return companyFromSlug(tenant), 0.6, "ATS subdomain " + domain
+		}
+	}
+	if !isGenericLabel(local) {
+		return companyFromSlug(local), 0.6, "ATS sender " + local
+	}
+
+	// "https://jobs.lever.co/initech/...", "https://globex.wd5.myworkdayjobs.com/..."
+	text := strings.Join(email.Links, "\n") + "\n" + email.Body
+	for _, re := range jobLinkPatterns {
+		if m := re.FindStringSubmatch(text); m != nil && !isGenericLabel(strings.ToLower(m[1])) {
+			return companyFromSlug(m[1]), 0.5, patternSource("job link", re.String())
+		}
+	}
+	return "", 0, ""
+}
+
+// job links of the ATS, the employer's slug is the first group
+var jobLinkPatterns = patterns(
+	`(?i)(?:job-)?boards\.greenhouse\.io/([\w-]+)`,
+	`(?i)jobs\.(?:eu\.)?lever\.co/([\w-]+)`,
+	`(?i)([\w-]+)\.wd\d+\.myworkdayjobs\.com`,
+	`(?i)([\w-]+)\.myworkdayjobs\.com`,
+	`(?i)(?:careers-)?([\w-]+)\.icims\.com`,
+	`(?i)jobs\.smartrecruiters\.com/([\w-]+)`,
+	`(?i)jobs\.ashbyhq\.com/([\w-]+)`,
+	`(?i)apply\.workable\.com/([\w-]+)`,
+)
+
+// parts of ATS addresses and links that never name the employer
+var genericLabels = []string{
+	"no-reply", "noreply", "donotreply", "do-not-reply", "reply", "notification", "bounce", "mail",
+	"apply", "hire", "jobs", "job-boards", "boards", "careers", "career", "recruiting", "talent",
+	"team", "hr", "info", "app", "www", "us", "eu", "api", "support",
+}
+
+func isGenericLabel(label string) bool {
+	if len(label) < 2 || strings.ContainsAny(label, "+=0123456789") {
+		return true // empty, wd5, bounces+1234
+	}
+	for _, g := range genericLabels {
+		if label == g || (len(g) > 4 && strings.Contains(label, g)) {
+			return true // "jobs-noreply", "notifications"
+		}
+	}
+	return false
+}
+
+// display names like "LinkedIn" or "Indeed Apply"
+func isATSName(name string) bool {
+	first, _, _ := strings.Cut(strings.ToLower(name), " ")
+	for _, p := range atsParsers {
+		if first == p.Name() {
+			return true
+		}
+	}
+	return false
+}
+
+// company of an employer's own address: the display name when it is the company
+// ("Acme Talent <no-reply@acme.com>"), otherwise the domain ("Jane Doe <jane@acme.com>")
+func employerCompany(displayName, addr string) (string, float64, string) {
+	domain := addressDomain(addr)
+	if domain == "" || freeMailDomains[domain] {
+		return "", 0, "" // a recruiter writing from a personal address
+	}
+	label := registrableLabel(domain)
+
+	name := cleanDisplayName(displayName)
+	if name != "" && sameCompany(name, label) {
+		return name, 0.8, "display name matching " + domain
+	}
+	return companyFromSlug(label), 0.6, "domain " + domain // the spelling may be off, "Piedpiper"
+}
+
+var freeMailDomains = map[string]bool{
+	"gmail.com": true, "googlemail.com": true, "outlook.com": true, "hotmail.com": true,
+	"live.com": true, "msn.com": true, "yahoo.com": true, "icloud.com": true, "me.com": true,
+	"aol.com": true, "proton.me": true, "protonmail.com": true, "gmx.com": true, "fastmail.com": true,
+}
+
+// suffixes under which companies register their domain
+var secondLevelSuffixes = []string{"co.uk", "org.uk", "ac.uk", "com.au", "co.nz", "co.jp", "co.in", "com.br", "com.mx", "co.za", "qc.ca"}
+
+// "careers.acme.co.uk" -> "acme"
+func registrableLabel(domain string) string {
+	labels := strings.Split(domain, ".")
+	n := len(labels)
+	if n < 2 {
+		return domain
+	}
+	for _, suffix := range secondLevelSuffixes {
+		if n > 2 && strings.HasSuffix(domain, "."+suffix) {
+			return labels[n-3]
+		}
+	}
+	return labels[n-2]
+}
+
+// words around the company in sender names, in English and French
+var displayNameNoise = regexp.MustCompile(`(?i)^(?:careers|jobs|recruiting|hiring)\s+(?:at|@|with)\s+|` +
+	`\s*(?:[-|,]\s*|\s)(?:via\s+\w+|talent acquisition|talent|careers?|carri[eè]res|recruiting|recrutement|recruitment|` +
+	`hiring(?: team)?|jobs|emplois|hr|people|team|notifications?)$`)
+
+// "Acme Talent" -> "Acme", "Careers at Acme" -> "Acme", "no-reply" -> ""
+func cleanDisplayName(name string) string {
+	name = strings.Trim(strings.TrimSpace(name), `"'`)
+	for {
+		cleaned := strings.TrimSpace(displayNameNoise.ReplaceAllString(name, ""))
+		if cleaned == name {
+			break
+		}
+		name = cleaned
+	}
+	if name == "" || strings.Contains(name, "@") || isGenericLabel(strings.ToLower(name)) {
+		return ""
+	}
+	return name
+}
+
+// "pied-piper" -> "Pied Piper"
+func companyFromSlug(slug string) string {
+	words := strings.FieldsFunc(slug, func(r rune) bool { return r == '-' || r == '_' || r == '.' })
+	for i, w := range words {
+		first, size := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToUpper(first)) + w[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// whether two names are the same company, "Wayne Enterprises" and "wayne", "Société Générale" and "societegenerale"
+func sameCompany(a, b string) bool {
+	a, b = squashName(a), squashName(b)
+	if len(a) < 3 || len(b) < 3 {
+		return a == b
+	}
+	return strings.Contains(a, b) || strings.Contains(b, a)
+}
+
+// lower case letters and digits without accents
+func squashName(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// "Jane <jane@Acme.com>" -> "acme.com"
+func addressDomain(addr string) string {
+	_, domain, ok := strings.Cut(addr, "@")
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(strings.Trim(domain, "<> "))
+}
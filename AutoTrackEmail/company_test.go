@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadEmailCapturesSender(t *testing.T) {
+	email, err := readEmailFile("testdata/emails/greenhouse_relayed.eml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email.FromName != "Globex Recruiting" || email.From != "no-reply@globex.example" {
+		t.Errorf("from = %q <%s>", email.FromName, email.From)
+	}
+	if email.ReturnPath != "bounces+1234567@us.greenhouse-mail.io" {
+		t.Errorf("return path = %q", email.ReturnPath)
+	}
+}
+
+func TestCompanyResolverResolve(t *testing.T) {
+	resolver := NewCompanyResolver(map[string]string{
+		"acme.io":        "ACME Inc.",
+		"Globex.Example": "Globex & Partners",
+	})
+
+	tests := []struct {
+		name       string
+		email      EmailData
+		want       string
+		wantMapped bool // from the mapping file
+	}{
+		{"display name of the employer", EmailData{FromName: "Vandelay Talent", From: "no-reply@vandelay.com"}, "Vandelay", false},
+		{"person at the employer", EmailData{FromName: "Jane Doe", From: "jane@careers.wayne.co.uk"}, "Wayne", false},
+		{"display name with accents", EmailData{FromName: "Société Générale Recrutement", From: "rh@societegenerale.example"}, "Société Générale", false},
+		{"personal address", EmailData{FromName: "Jane Doe", From: "jane.doe@gmail.com"}, "", false},
+		{"mapped domain", EmailData{FromName: "Acme Talent", From: "talent@mail.acme.io"}, "ACME Inc.", true},
+		{"mapped reply-to", EmailData{From: "no-reply@hire.lever.co", ReplyTo: "jane@globex.example"}, "Globex & Partners", true},
+		{"relay display name", EmailData{FromName: "Initech", From: "no-reply@hire.lever.co"}, "Initech", false},
+		{"relay careers display name", EmailData{FromName: "Careers at Pied Piper", From: "no-reply@ashbyhq.com"}, "Pied Piper", false},
+		{"relay reply-to", EmailData{FromName: "Greenhouse", From: "no-reply@us.greenhouse-mail.io", ReplyTo: "jane@hooli.com"}, "Hooli", false},
+		{"relay subdomain", EmailData{FromName: "Careers", From: "careers@hooli.icims.com"}, "Hooli", false},
+		{"relay tenant", EmailData{FromName: "Workday", From: "umbrella@myworkday.com"}, "Umbrella", false},
+		{"relay job link", EmailData{From: "no-reply@hire.lever.co", Links: []string{"https://jobs.lever.co/pied-piper/42"}}, "Pied Piper", false},
+		{"relay job link in body", EmailData{From: "notifications@smartrecruiters.com", Body: "See https://jobs.smartrecruiters.com/Vandelay/7 for details"}, "Vandelay", false},
+		{"job board", EmailData{FromName: "LinkedIn", From: "jobs-noreply@linkedin.com"}, "", false},
+		{"job board apply", EmailData{FromName: "Indeed Apply", From: "indeedapply@indeed.com"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, confidence, _ := resolver.Resolve(tt.email)
+			if mapped := confidence == confidenceRule; got != tt.want || mapped != tt.wantMapped {
+				t.Errorf("Resolve() = %q, %v, want %q, mapped %v", got, confidence, tt.want, tt.wantMapped)
+			}
+		})
+	}
+}
+
+func TestParserRegistryResolvesCompany(t *testing.T) {
+	parsers := newDefaultParserRegistry(nil, NewCompanyResolver(map[string]string{"hire.lever.co": "Mapped Co"}))
+
+	tests := []struct {
+		name  string
+		email EmailData
+		want  string
+	}{
+		// the generic parser only finds the subject prefix
+		{"sender beats generic", EmailData{FromName: "Careers", From: "careers@hooli.example", Subject: "Application received - Data Scientist position"}, "Hooli"},
+		{"generic kept when it agrees", EmailData{FromName: "Jane Recruiter", From: "jane@wayne.example", Subject: "Next steps", Body: "Thanks for your interest in the role at Wayne Enterprises."}, "Wayne Enterprises"},
+		{"dedicated parser kept", EmailData{FromName: "Globex Careers", From: "globex@myworkday.com", Subject: "Thank you for applying to the Analyst position at Globex Corporation"}, "Globex Corporation"},
+		{"mapping beats dedicated parser", EmailData{FromName: "Initech", From: "no-reply@hire.lever.co", Subject: "Thank you for your application to Initech Labs"}, "Mapped Co"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := parsers.Parse(tt.email)
+			if job == nil || job.Company != tt.want {
+				t.Errorf("got %+v, want company %q", job, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCompanyDomains(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "companies.json")
+	if err := os.WriteFile(path, []byte(`{"Acme.com": "Acme Corporation"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver, err := LoadCompanyDomains(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _, _ := resolver.Resolve(EmailData{From: "jobs@careers.acme.com"}); got != "Acme Corporation" {
+		t.Errorf("got %q", got)
+	}
+
+	if _, err := LoadCompanyDomains("companies.example.yaml"); err != nil {
+		t.Errorf("example file: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(`["acme.com"]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCompanyDomains(path); err == nil {
+		t.Error("a list should be rejected")
+	}
+}
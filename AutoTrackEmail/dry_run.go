@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// DryRunReport is one line of the --dry-run output: an email and what a real run would do with it
+type DryRunReport struct {
+	UID        uint32      `json:"uid"`
+	MessageID  string      `json:"message_id,omitempty"`
+	From       string      `json:"from"`
+	Subject    string      `json:"subject"`
+	Date       string      `json:"date,omitempty"` // not known for emails skipped by their envelope
+	Parser     string      `json:"parser,omitempty"`
+	Rule       string      `json:"rule,omitempty"` // rule of RULES_FILE that matched
+	Job        *Job        `json:"job,omitempty"`
+	Confidence *Confidence `json:"confidence,omitempty"`
+	Action     string      `json:"action,omitempty"`  // create, update or review
+	Skipped    string      `json:"skipped,omitempty"` // why no job would be saved
+	Explain    *Sources    `json:"explain,omitempty"` // with --explain
+}
+
+// DryRun writes a DryRunReport per email as NDJSON instead of saving jobs
+type DryRun struct {
+	mu      sync.Mutex
+	out     *json.Encoder
+	explain bool
+}
+
+// NewDryRun writes to w, explain adds the pattern that found each field
+func NewDryRun(w io.Writer, explain bool) *DryRun {
+	out := json.NewEncoder(w)
+	out.SetEscapeHTML(false) // keep Message-IDs and patterns readable
+	return &DryRun{out: out, explain: explain}
+}
+
+// Skipped reports an email that is not downloaded, see reportSkippedEmail
+func (d *DryRun) Skipped(uid uint32, from, subject, reason string) {
+	d.write(DryRunReport{UID: uid, From: from, Subject: subject, Skipped: reason})
+}
+
+// Email parses the email like a real run and reports the job and what would be done with it
+func (d *DryRun) Email(email EmailData) {
+	report := DryRunReport{
+		UID:       email.UID,
+		MessageID: email.MessageID,
+		From:      email.From,
+		Subject:   email.Subject,
+		Date:      email.Date,
+	}
+
+	parser := defaultParsers.ParserFor(email)
+	report.Parser = parser.Name()
+	rule := jobRules.Match(email)
+	if rule != nil {
+		report.Rule = rule.Name
+	}
+
+	job := defaultParsers.Parse(email)
+	switch {
+	case job != nil:
+		report.Job, report.Confidence = job, &job.Confidence
+		report.Action = dryRunAction(*job)
+		if d.explain {
+			report.Explain = &job.Sources
+		}
+	case rule != nil && rule.Ignore:
+		report.Skipped = fmt.Sprintf("ignored by rule %q", rule.Name)
+	default:
+		report.Skipped = "no company or title found"
+	}
+	d.write(report)
+}
+
+// what processJob would do. A follow-up updates the job it is about when the server has one,
+// otherwise it is created or reviewed like the others: "update, else create"
+func dryRunAction(job Job) string {
+	action := "create"
+	if needsReview(job) {
+		action = "review"
+	}
+	// without a company findMatchingJob does not look for the job
+	if isFollowUp(job) && job.Company != "" && job.Company != "Unknown Company" {
+		return "update, else " + action
+	}
+	return action
+}
+
+func (d *DryRun) write(report DryRunReport) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.out.Encode(report)
+}
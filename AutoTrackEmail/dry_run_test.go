@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func decodeReports(t *testing.T, out *bytes.Buffer) []DryRunReport {
+	t.Helper()
+	var reports []DryRunReport
+	dec := json.NewDecoder(out)
+	for dec.More() {
+		var report DryRunReport
+		if err := dec.Decode(&report); err != nil {
+			t.Fatal(err)
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+func TestDryRunProcessorOnlyReports(t *testing.T) {
+	api := startFakeJobAPI(t)
+	addr, be := startTestIMAPServer(t)
+	c, err := dialTestIMAPServer(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Logout()
+
+	addTestEmail(t, be, "Weekly newsletter")
+	addTestEmail(t, be, "Thank you for your application to Globex")
+	addTestEmail(t, be, "Application update: interview for the Data Engineer role")
+
+	var out bytes.Buffer
+	report := NewDryRun(&out, false)
+	reportSkippedEmail = report.Skipped
+	t.Cleanup(func() { reportSkippedEmail = nil })
+
+	checkpoints := &CheckpointStore{Path: filepath.Join(t.TempDir(), "watcher_state.json")}
+	process := newDryRunProcessor(checkpoints, "INBOX", report)
+	for range 2 { // the second run has nothing new
+		if err := process(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reports := map[string]DryRunReport{}
+	for _, r := range decodeReports(t, &out) {
+		if _, dup := reports[r.Subject]; dup {
+			t.Errorf("%q reported twice", r.Subject)
+		}
+		reports[r.Subject] = r
+	}
+	if r := reports["Weekly newsletter"]; r.Skipped != "no job keyword in the subject" || r.Job != nil {
+		t.Errorf("newsletter report = %+v", r)
+	}
+	// no title and only the sender's domain for the company
+	if r := reports["Thank you for your application to Globex"]; r.Job == nil || r.Parser != "generic" || r.Action != "review" || r.Confidence == nil || r.Explain != nil {
+		t.Errorf("application report = %+v", r)
+	}
+	if r := reports["Application update: interview for the Data Engineer role"]; r.Job == nil || r.Job.Title != "Data Engineer" || r.Action != "update, else create" {
+		t.Errorf("interview report = %+v", r)
+	}
+
+	if got := api.requestLog(); got != "" {
+		t.Errorf("dry run sent requests: %s", got)
+	}
+	if _, err := os.Stat(checkpoints.Path); !os.IsNotExist(err) {
+		t.Errorf("dry run saved the checkpoint (%v)", err)
+	}
+}
+
+func TestDryRunExplain(t *testing.T) {
+	var out bytes.Buffer
+	report := NewDryRun(&out, true)
+	for _, name := range []string{"lever_html_only.eml", "generic_interview.eml"} {
+		email, err := readEmailFile(filepath.Join("testdata", "emails", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		report.Email(email)
+	}
+
+	reports := decodeReports(t, &out)
+	if len(reports) != 2 {
+		t.Fatalf("got %d reports, want 2", len(reports))
+	}
+
+	lever := reports[0].Explain
+	if lever == nil || !strings.HasPrefix(lever.Company, "lever subject /(?i)") || !strings.HasPrefix(lever.Title, "lever body /") ||
+		lever.Status != `lever subject keyword "thank you"` {
+		t.Errorf("lever explain = %+v", lever)
+	}
+
+	generic := reports[1].Explain
+	if generic == nil || generic.Title != `generic subject /(?i)for\s+the\s+([^-]+?)(?:\s+position|\s+role)/` ||
+		generic.Status != `generic subject keyword "interview"` {
+		t.Errorf("generic explain = %+v", generic)
+	}
+	if !strings.Contains(generic.Company, "sender") {
+		t.Errorf("generic company explain = %q, want the sender", generic.Company)
+	}
+}
+
+func TestDryRunActionFallsBackLikeProcessJob(t *testing.T) {
+	sure := Confidence{Company: 0.9, Title: 0.9, Status: 0.8}
+	unsure := Confidence{Company: 0.3, Title: 0.9, Status: 0.8}
+	for _, tt := range []struct {
+		job  Job
+		want string
+	}{
+		{Job{Company: "Acme", Status: "applied", Confidence: sure}, "create"},
+		{Job{Company: "Acme", Status: "applied", Confidence: unsure}, "review"},
+		{Job{Company: "Acme", Status: "rejected", Confidence: sure}, "update, else create"},
+		{Job{Company: "Acme", Status: "interview", Confidence: unsure}, "update, else review"},
+		{Job{Company: "Unknown Company", Status: "offer", Confidence: unsure}, "review"},
+	} {
+		if got := dryRunAction(tt.job); got != tt.want {
+			t.Errorf("dryRunAction(%s at %s, confidence %.1f) = %q, want %q", tt.job.Status, tt.job.Company, tt.job.Confidence.Min(), got, tt.want)
+		}
+	}
+}
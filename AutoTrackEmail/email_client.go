@@ -1,135 +1,305 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"mime"
-	"mime/multipart"
+	"net/mail"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/emersion/go-imap"
 	"github.com/emersion/go-imap/client"
 	"github.com/emersion/go-message"
+	"github.com/emersion/go-message/charset"
 )
 
+func init() {
+	// decode the charsets go-message knows (ISO-8859-*, windows-125x, ...) in envelopes too,
+	// importing the charset package already does it for messages
+	imap.CharsetReader = charset.Reader
+}
+
 type EmailData struct {
-	Subject string
-	Date    string
-	Body    string
+	UID        uint32
+	MessageID  string
+	From       string // sender address
+	FromName   string // sender display name, "Acme Talent" in "Acme Talent <no-reply@acme.com>"
+	ReplyTo    string // first Reply-To address, a recruiter's own address for many ATS emails
+	ReturnPath string // envelope sender (Return-Path), the ATS's bounce address for relayed emails
+	Subject    string
+	Date       string
+	Body       string
+	Header     message.Header // all headers, parsers use them to recognise the sender's system
+	Links      []string       // http(s) links of the HTML body, job URLs are often only there
 }
 
-// Connect to Gmail IMAP server
-func ConnectToGmail(email, password string) (*client.Client, error) {
-	c, err := client.DialTLS("imap.gmail.com:993", nil)
+// FetchNewEmails retrieves job-related emails of the mailbox that arrived after the checkpoint,
+// whether they were read or not, and returns the checkpoint to save once they are handled.
+// The checkpoint moves past every new email, job emails whose body could not be downloaded
+// are kept in it to be fetched again. A checkpoint for another mailbox or UIDVALIDITY means a full rescan
+func FetchNewEmails(c *client.Client, mailbox string, cp Checkpoint) ([]EmailData, Checkpoint, error) {
+	// Select mailbox
+	mbox, err := c.Select(mailbox, false)
 	if err != nil {
-		return nil, err
+		return nil, cp, err
 	}
 
-	if err = c.Login(email, password); err != nil {
-		c.Logout()
-		return nil, err
+	if cp.Mailbox != mbox.Name || cp.UIDValidity != mbox.UidValidity {
+		if cp.UIDValidity != 0 {
+			log.Printf("UIDVALIDITY of %s changed (%d -> %d), rescanning the whole mailbox", mbox.Name, cp.UIDValidity, mbox.UidValidity)
+		}
+		cp = Checkpoint{Mailbox: mbox.Name, UIDValidity: mbox.UidValidity}
 	}
 
-	log.Println("Successfully logged in to Gmail")
-	return c, nil
-}
-
-// FetchUnreadEmails retrieves unread job-related emails
+	if mbox.Messages == 0 {
+		log.Printf("No messages in %s", mbox.Name)
+		cp.Unfetched = nil
+		return []EmailData{}, cp, nil
+	}
 
-func FetchUnreadEmails(c *client.Client) ([]EmailData, error) {
-	// Select inbox
-	mbox, err := c.Select("INBOX", false)
+	// Search for messages after the last processed UID
+	criteria := imap.NewSearchCriteria()
+	criteria.Uid = new(imap.SeqSet)
+	criteria.Uid.AddRange(cp.LastUID+1, 0) // LastUID+1:*
+	found, err := c.UidSearch(criteria)
 	if err != nil {
-		return nil, err
+		return nil, cp, err
 	}
 
-	if mbox.Messages == 0 {
-		log.Println("No messages in inbox")
-		return []EmailData{}, nil
+	// "n:*" always matches the newest message, even when its UID is below n
+	var uids []uint32
+	for _, uid := range found {
+		if uid > cp.LastUID {
+			uids = append(uids, uid)
+		}
 	}
 
-	// Search for unread messages
-	criteria := imap.NewSearchCriteria()
-	criteria.WithoutFlags = []string{"\\Seen"}       // get unread message
-	criteria.Since = time.Now().Add(-24 * time.Hour) // get only last 24 hour message
-	seqNums, err := c.Search(criteria)
+	// the job emails not downloaded last time, unless they were deleted since
+	candidates, err := existingUIDs(c, cp.Unfetched)
 	if err != nil {
-		return nil, err
+		return nil, cp, err
+	}
+
+	if len(uids) == 0 && len(candidates) == 0 {
+		log.Println("No new emails found")
+		cp.Unfetched = nil
+		return []EmailData{}, cp, nil
+	}
+
+	if len(uids) > 0 {
+		seqSet := new(imap.SeqSet)
+		seqSet.AddNum(uids...)
+
+		// First pass: envelopes only, so non job emails are never downloaded
+		jobEmails, err := findJobEmails(c, seqSet)
+		if err != nil {
+			return nil, cp, err
+		}
+		candidates = append(candidates, jobEmails...)
+		cp.LastUID = max(cp.LastUID, slices.Max(uids))
 	}
 
-	if len(seqNums) == 0 {
-		log.Println("No unread emails found")
-		return []EmailData{}, nil
+	if len(candidates) == 0 {
+		log.Println("No new job-related emails found")
+		cp.Unfetched = nil
+		return []EmailData{}, cp, nil
 	}
 
-	seqSet := new(imap.SeqSet)
-	seqSet.AddNum(seqNums...)
+	candidateSet := new(imap.SeqSet)
+	candidateSet.AddNum(candidates...)
 
-	//Fetch messages
-	section := &imap.BodySectionName{}
+	// Second pass: full message with BODY.PEEK so the email stays unread
+	section := &imap.BodySectionName{Peek: true}
 	messages := make(chan *imap.Message, 10)
 	done := make(chan error, 1)
 	go func() {
-		done <- c.Fetch(seqSet, []imap.FetchItem{imap.FetchEnvelope, section.FetchItem()}, messages)
+		done <- c.UidFetch(candidateSet, []imap.FetchItem{imap.FetchUid, imap.FetchInternalDate, section.FetchItem()}, messages)
 	}()
 
 	var emails []EmailData
+	downloaded := map[uint32]bool{}
 
 	//process messages
 	for msg := range messages {
 		r := msg.GetBody(section)
-		//if no body skip it
+		//if no body, fetch it again next time
 		if r == nil {
-			log.Println("Empty body, skipping...")
 			continue
 		}
+		downloaded[msg.Uid] = true
 
-		mr, err := message.Read(r)
+		email, err := readEmail(r, msg.InternalDate)
 		if err != nil {
-			log.Println("Message parse error:", err)
+			// fetching it again would not help
+			log.Printf("✗ Skipping email UID %d of %s, the message cannot be parsed: %v", msg.Uid, mbox.Name, err)
 			continue
 		}
+		email.UID = msg.Uid
 
-		header := mr.Header
-		subject := header.Get("Subject")
-		dateStr := header.Get("Date")
+		log.Printf("Found job email: %s (%s)\n", email.Subject, email.Date)
+		emails = append(emails, email)
+	}
 
-		//parse the date string
-		date, err := parseEmailDate(dateStr)
-		if err != nil {
-			log.Println("Error parsing date:", err)
-			continue
+	// Wait for fetch to complete
+	if err := <-done; err != nil {
+		return nil, cp, err
+	}
+
+	cp.Unfetched = nil
+	for _, uid := range candidates {
+		if !downloaded[uid] {
+			log.Printf("Could not download email UID %d of %s, trying again next time", uid, mbox.Name)
+			cp.Unfetched = append(cp.Unfetched, uid)
 		}
+	}
+	return emails, cp, nil
+}
+
+// the UIDs that are still in the selected mailbox
+func existingUIDs(c *client.Client, uids []uint32) ([]uint32, error) {
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	criteria := imap.NewSearchCriteria()
+	criteria.Uid = new(imap.SeqSet)
+	criteria.Uid.AddNum(uids...)
+	return c.UidSearch(criteria)
+}
 
-		//Filter job-related emails
-		if isJobRelatedEmail(subject) {
-			log.Printf("Found job email: %s (%s)\n", subject, date.Format("2006-01-02"))
+// readEmail parses a raw RFC 5322 message. received is used when the Date header is missing or broken
+func readEmail(r io.Reader, received time.Time) (EmailData, error) {
+	mr, err := message.Read(r)
+	if isUnknownDecoding(err) {
+		log.Println("Email is not fully decoded:", err)
+	} else if err != nil {
+		return EmailData{}, err
+	}
+
+	header := mr.Header
+	dateStr := header.Get("Date")
 
-			//extract email body
-			body := extractEmailBody(mr)
+	//parse the date string, fall back to when the server received it
+	date, err := parseEmailDate(dateStr)
+	if err != nil {
+		log.Printf("Error parsing date %q, using the received date: %v", dateStr, err)
+		date = received
+	}
 
-			emails = append(emails, EmailData{
-				Subject: subject,
-				Date:    date.Format("2006-01-02"),
-				Body:    body,
-			})
+	fromName, from := parseAddress(header.Get("From"))
+	_, replyTo := parseAddress(header.Get("Reply-To"))
+	_, returnPath := parseAddress(header.Get("Return-Path"))
+	body, links := extractEmailBody(mr)
+	return EmailData{
+		MessageID:  header.Get("Message-Id"),
+		From:       from,
+		FromName:   fromName,
+		ReplyTo:    replyTo,
+		ReturnPath: returnPath,
+		Subject:    headerText(header, "Subject"),
+		Date:       date.Format("2006-01-02"),
+		Body:       body,
+		Header:     header,
+		Links:      links,
+	}, nil
+}
+
+// the entity is still usable, just not converted to UTF-8 or not decoded at all
+func isUnknownDecoding(err error) bool {
+	return err != nil && (message.IsUnknownCharset(err) || message.IsUnknownEncoding(err))
+}
+
+// header value with RFC 2047 encoded words ("=?UTF-8?B?...?=") decoded, raw if they are broken
+func headerText(header message.Header, key string) string {
+	value, err := header.Text(key)
+	if err != nil {
+		log.Printf("Error decoding %s header: %v", key, err)
+		return header.Get(key)
+	}
+	return value
+}
+
+// fetch envelopes and return the UIDs of job-related emails
+func findJobEmails(c *client.Client, uids *imap.SeqSet) ([]uint32, error) {
+	messages := make(chan *imap.Message, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.UidFetch(uids, []imap.FetchItem{imap.FetchUid, imap.FetchEnvelope}, messages)
+	}()
+
+	var candidates []uint32
+	for msg := range messages {
+		if msg.Envelope == nil {
+			continue
 		}
+		from, subject := envelopeSender(msg.Envelope), msg.Envelope.Subject
+		if reason := envelopeSkipReason(from, subject); reason != "" {
+			if reportSkippedEmail != nil {
+				reportSkippedEmail(msg.Uid, from, subject, reason)
+			}
+			continue
+		}
+		candidates = append(candidates, msg.Uid)
 	}
 
-	// Wait for fetch to complete
 	if err := <-done; err != nil {
 		return nil, err
 	}
+	return candidates, nil
+}
+
+// decodes display names in any charset go-message knows
+var addressParser = mail.AddressParser{WordDecoder: &mime.WordDecoder{CharsetReader: charset.Reader}}
+
+// display name and address of the first address of a header,
+// "Acme Careers <jobs@acme.com>" -> "Acme Careers", "jobs@acme.com"
+func parseAddress(value string) (name, address string) {
+	if strings.TrimSpace(value) == "" {
+		return "", ""
+	}
+	if addrs, err := addressParser.ParseList(value); err == nil && len(addrs) > 0 {
+		return addrs[0].Name, addrs[0].Address
+	}
+	return "", strings.Trim(strings.TrimSpace(value), "<>")
+}
 
-	return emails, nil
+// rules from the rules file decide first, then the subject keywords
+func isJobEmail(from, subject string) bool {
+	return envelopeSkipReason(from, subject) == ""
+}
+
+// why an email is not downloaded, "" for job emails
+func envelopeSkipReason(from, subject string) string {
+	if rule := jobRules.matchEnvelopeRule(from, subject); rule != nil {
+		if rule.Ignore {
+			return fmt.Sprintf("ignored by rule %q", rule.Name)
+		}
+		return ""
+	}
+	if !isJobRelatedEmail(subject) {
+		return "no job keyword in the subject"
+	}
+	return ""
+}
+
+// called with the emails FetchNewEmails skips by their envelope, used by --dry-run
+var reportSkippedEmail func(uid uint32, from, subject, reason string)
+
+func envelopeSender(env *imap.Envelope) string {
+	if len(env.From) == 0 {
+		return ""
+	}
+	return env.From[0].Address()
 }
 
 // check if email is job-related by subject
 func isJobRelatedEmail(subject string) bool {
 	subject = strings.ToLower(subject)
 
-	jobKeywords := []string{"application", "thank you for apply", "next step"} // will add more
+	jobKeywords := []string{"application", "thank you for apply", "thanks for apply", "next step"} // will add more
 
 	for _, keyword := range jobKeywords {
 		if strings.Contains(subject, keyword) {
@@ -140,134 +310,86 @@ func isJobRelatedEmail(subject string) bool {
 	return false
 }
 
-// parse Gmail date format (if change provider need to be changed)
-// Gmail uses RFC 2822 format: "Mon, 02 Jan 2006 15:04:05 -0700"
+// parse the Date header. net/mail handles RFC 5322 including the obsolete forms
+// (two digit years, zone names like GMT/EST, comments such as "(UTC)"),
+// the other layouts are non standard ones some senders still use
 func parseEmailDate(dateStr string) (time.Time, error) {
+	dateStr = strings.TrimSpace(dateStr)
+	if dateStr == "" {
+		return time.Time{}, errors.New("no Date header")
+	}
 
-	// Remove any timezone abbreviation in parentheses at the end
-	if idx := strings.Index(dateStr, " ("); idx != -1 {
-		dateStr = dateStr[:idx]
+	date, err := mail.ParseDate(dateStr)
+	if err == nil {
+		return date, nil
 	}
 
-	// Try multiple common email date formats
 	formats := []string{
-		"Mon, 02 Jan 2006 15:04:05 -0700", // Standard RFC 2822
-		"Mon, 2 Jan 2006 15:04:05 -0700",  // Single digit day
-		"2 Jan 2006 15:04:05 -0700",       // Without day of week
-		"Mon, 02 Jan 2006 15:04:05 MST",   // With timezone name
-		"Mon, 2 Jan 2006 15:04:05 MST",    // Single digit day + timezone name
+		time.ANSIC,    // "Mon Jan _2 15:04:05 2006"
+		time.UnixDate, // "Mon Jan _2 15:04:05 MST 2006"
+		time.RFC3339,
+		"2006-01-02 15:04:05 -0700",
 	}
-
 	for _, format := range formats {
 		if date, err := time.Parse(format, dateStr); err == nil {
 			return date, nil
 		}
 	}
 
-	// If all parsing attempts fail, return the error from the standard format
-	return time.Parse("Mon, 02 Jan 2006 15:04:05 -0700", dateStr)
-}
-
-// extract body from email message
-func extractEmailBody(mr *message.Entity) string {
-	// check if it's a multipart message
-	if mr.Header.Get("Content-Type") != "" {
-		mediaType, params, err := mime.ParseMediaType(mr.Header.Get("Content-Type"))
-		if err == nil && strings.HasPrefix(mediaType, "multipart/") {
-			return extractMultipartBody(mr, params["boundary"])
-		}
-	}
-
-	//single part message
-	body, err := io.ReadAll(mr.Body)
-	if err != nil {
-		log.Println("Error reading email body:", err)
-		return ""
-	}
-
-	return string(body)
-
+	return time.Time{}, err
 }
 
-// multipart email message
-func extractMultipartBody(mr *message.Entity, boundary string) string {
-	multipartReader := multipart.NewReader(mr.Body, boundary)
-
+// extract the text body of an email and the links of its HTML body. Parts are walked
+// depth first, so multipart/alternative nested in multipart/mixed is found too, and their
+// transfer encoding (base64, quoted-printable) and charset are decoded by go-message.
+// The first text/plain part is preferred, the first text/html one is converted otherwise
+func extractEmailBody(mr *message.Entity) (string, []string) {
 	var textBody, htmlBody string
+	var hasText, hasHTML bool
+	var links []string
+
+	err := mr.Walk(func(path []int, part *message.Entity, err error) error {
+		if isUnknownDecoding(err) {
+			log.Println("Email part is not fully decoded:", err)
+		} else if err != nil {
+			return err
+		}
 
-	for {
-		part, err := multipartReader.NextPart()
-		if err == io.EOF {
-			break
+		if disposition, _, _ := part.Header.ContentDisposition(); disposition == "attachment" {
+			return nil
 		}
-		if err != nil {
-			log.Println("Error reading multipart:", err)
-			break
+		mediaType, _, _ := part.Header.ContentType()
+		if mediaType == "" && len(path) == 0 {
+			mediaType = "text/plain" // RFC 2045 default
+		}
+		if mediaType != "text/plain" && mediaType != "text/html" {
+			return nil
+		}
+		if (mediaType == "text/plain" && hasText) || (mediaType == "text/html" && hasHTML) {
+			return nil
 		}
 
-		contentType := part.Header.Get("Content-Type")
-		mediaType, _, _ := mime.ParseMediaType(contentType)
-
-		body, err := io.ReadAll(part)
+		body, err := io.ReadAll(part.Body)
 		if err != nil {
-			continue
+			log.Println("Error reading email body:", err)
+			return nil
 		}
 
-		switch mediaType {
-		case "text/plain":
-			textBody = string(body)
-		case "text/html":
-			if textBody == "" { //only use HTML if no plain text
-				htmlBody = stripHTMLTags(string(body))
-			}
+		if mediaType == "text/plain" {
+			textBody, hasText = string(body), true
+		} else {
+			htmlBody, links = htmlToText(string(body))
+			hasHTML = true
 		}
-
-		part.Close()
+		return nil
+	})
+	if err != nil {
+		log.Println("Error reading multipart:", err)
 	}
 
 	// Prefer plain text over HTML
-	if textBody != "" {
-		return textBody
+	if strings.TrimSpace(textBody) != "" {
+		return textBody, links
 	}
-	return htmlBody
-}
-
-// removes basic HTML tags (simple implementation)
-func stripHTMLTags(html string) string {
-	// very basic HTML stripper
-	lines := strings.Split(html, "\n")
-	var result []string
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		// Remove common HTML tags
-		line = strings.ReplaceAll(line, "<br>", "\n")
-		line = strings.ReplaceAll(line, "<br/>", "\n")
-		line = strings.ReplaceAll(line, "<br />", "\n")
-		line = strings.ReplaceAll(line, "<p>", "")
-		line = strings.ReplaceAll(line, "</p>", "\n")
-		line = strings.ReplaceAll(line, "<div>", "")
-		line = strings.ReplaceAll(line, "</div>", "\n")
-
-		// Basic tag removal (not comprehensive)
-		for strings.Contains(line, "<") && strings.Contains(line, ">") {
-			start := strings.Index(line, "<")
-			end := strings.Index(line[start:], ">")
-			if end == -1 {
-				break
-			}
-			line = line[:start] + line[start+end+1:]
-		}
-
-		line = strings.TrimSpace(line)
-		if line != "" {
-			result = append(result, line)
-		}
-	}
-
-	return strings.Join(result, "\n")
+	return htmlBody, links
 }
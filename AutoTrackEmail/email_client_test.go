@@ -0,0 +1,302 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+func TestFetchNewEmailsResumesFromCheckpoint(t *testing.T) {
+	addr, be := startTestIMAPServer(t)
+	c, err := dialTestIMAPServer(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Logout()
+
+	addTestEmail(t, be, "Your application was sent to Acme")
+
+	emails, cp, err := FetchNewEmails(c, "INBOX", Checkpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 1 || emails[0].Subject != "Your application was sent to Acme" {
+		t.Fatalf("first run got %+v, want the Acme email", emails)
+	}
+	if cp.Mailbox != "INBOX" || cp.UIDValidity == 0 || cp.LastUID != emails[0].UID {
+		t.Fatalf("checkpoint = %+v, want INBOX up to UID %d", cp, emails[0].UID)
+	}
+
+	// nothing new: the last message must not be returned again
+	emails, cp, err = FetchNewEmails(c, "INBOX", cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 0 {
+		t.Fatalf("second run got %d emails, want none", len(emails))
+	}
+
+	addTestEmail(t, be, "Thank you for your application to Globex")
+	emails, _, err = FetchNewEmails(c, "INBOX", cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 1 || emails[0].Subject != "Thank you for your application to Globex" {
+		t.Fatalf("third run got %+v, want only the Globex email", emails)
+	}
+}
+
+func TestFetchNewEmailsRetriesUndownloadedEmails(t *testing.T) {
+	addr, be := startTestIMAPServer(t)
+	c, err := dialTestIMAPServer(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Logout()
+
+	addTestEmail(t, be, "Your application was sent to Acme")
+	addTestEmail(t, be, "Thank you for your application to Globex")
+	emails, cp, err := FetchNewEmails(c, "INBOX", Checkpoint{})
+	if err != nil || len(emails) != 2 || len(cp.Unfetched) != 0 {
+		t.Fatalf("first run got %d emails, checkpoint %+v, %v", len(emails), cp, err)
+	}
+
+	// as if the Acme body had not come back, 99 was deleted since
+	cp.Unfetched = []uint32{emails[0].UID, 99}
+	lastUID := cp.LastUID
+	emails, cp, err = FetchNewEmails(c, "INBOX", cp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 1 || emails[0].Subject != "Your application was sent to Acme" {
+		t.Fatalf("got %+v, want only the Acme email again", emails)
+	}
+	if cp.LastUID != lastUID || len(cp.Unfetched) != 0 {
+		t.Errorf("checkpoint = %+v, want UID %d and nothing left to fetch", cp, lastUID)
+	}
+}
+
+func TestFetchNewEmailsRescansWhenUIDValidityChanges(t *testing.T) {
+	addr, be := startTestIMAPServer(t)
+	c, err := dialTestIMAPServer(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Logout()
+
+	addTestEmail(t, be, "Your application was sent to Acme")
+	addTestEmail(t, be, "Thank you for your application to Globex")
+
+	stale := Checkpoint{Mailbox: "INBOX", UIDValidity: 12345, LastUID: 1000}
+	emails, cp, err := FetchNewEmails(c, "INBOX", stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 2 {
+		t.Fatalf("got %d emails, want both emails after UIDVALIDITY change", len(emails))
+	}
+	if cp.UIDValidity == stale.UIDValidity {
+		t.Errorf("checkpoint kept the stale UIDVALIDITY %d", cp.UIDValidity)
+	}
+}
+
+func TestFetchNewEmailsSkipsOtherEmails(t *testing.T) {
+	addr, be := startTestIMAPServer(t)
+	c, err := dialTestIMAPServer(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Logout()
+
+	addTestEmail(t, be, "Weekly newsletter")
+	addTestEmail(t, be, "Your application was sent to Acme")
+	addTestEmail(t, be, "Lunch on Friday?")
+
+	emails, cp, err := FetchNewEmails(c, "INBOX", Checkpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 1 || emails[0].Subject != "Your application was sent to Acme" {
+		t.Fatalf("got %+v, want only the Acme email", emails)
+	}
+	// the checkpoint still moves past the emails that were skipped
+	if cp.LastUID <= emails[0].UID {
+		t.Errorf("checkpoint stopped at UID %d, want past the last email", cp.LastUID)
+	}
+}
+
+func TestFetchNewEmailsDecodesEncodedSubjects(t *testing.T) {
+	addr, be := startTestIMAPServer(t)
+	c, err := dialTestIMAPServer(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Logout()
+
+	// "Candidature reçue – Your application", the dash only exists in windows-1252
+	addTestEmail(t, be, "=?windows-1252?B?Q2FuZGlkYXR1cmUgcmXndWUgliBZb3VyIGFwcGxpY2F0aW9u?=")
+	addTestEmail(t, be, "=?UTF-8?B?TGV0J3MgZ3JhYiBsdW5jaA==?=") // "Let's grab lunch"
+
+	emails, _, err := FetchNewEmails(c, "INBOX", Checkpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(emails) != 1 || emails[0].Subject != "Candidature reçue – Your application" {
+		t.Fatalf("got %+v, want only the decoded application email", emails)
+	}
+}
+
+func TestReadEmailDecodesNestedParts(t *testing.T) {
+	f, err := os.Open("testdata/emails/french_nested_latin1.eml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	email, err := readEmail(f, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if email.Subject != "Candidature reçue – Application received" {
+		t.Errorf("subject = %q", email.Subject)
+	}
+	if email.From != "carrieres@desjardins.example" || email.FromName != "Desjardins Carrières" {
+		t.Errorf("from = %q <%s>", email.FromName, email.From)
+	}
+	// quoted-printable ISO-8859-1 text part, soft line breaks joined
+	if !strings.Contains(email.Body, "Nous avons bien reçu votre candidature pour le poste d'Analyste de données.") {
+		t.Errorf("text part not decoded:\n%s", email.Body)
+	}
+	if strings.Contains(email.Body, "PDF") {
+		t.Errorf("attachment read as body:\n%s", email.Body)
+	}
+	// links come from the base64 windows-1252 HTML part
+	if want := []string{"https://emplois.desjardins.example/poste/4521"}; !slices.Equal(email.Links, want) {
+		t.Errorf("links = %q, want %q", email.Links, want)
+	}
+}
+
+func TestExtractEmailBodyFallsBackToHTML(t *testing.T) {
+	raw := "Content-Type: multipart/alternative; boundary=b\r\n\r\n" +
+		"--b\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n \r\n" +
+		"--b\r\nContent-Type: text/html; charset=iso-8859-15\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n" +
+		"<p>Poste : D=E9veloppeur =A4 Montr=E9al</p>\r\n" +
+		"--b--\r\n"
+
+	email, err := readEmail(strings.NewReader(raw), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email.Body != "Poste : Développeur € Montréal" {
+		t.Errorf("body = %q", email.Body)
+	}
+}
+
+func TestParsePostAction(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    PostAction
+		wantErr bool
+	}{
+		{in: "", want: PostAction{Kind: PostActionNone}},
+		{in: "unread", want: PostAction{Kind: PostActionNone}},
+		{in: "none", want: PostAction{Kind: PostActionNone}},
+		{in: "READ", want: PostAction{Kind: PostActionMarkRead}},
+		{in: "label:Job Applications", want: PostAction{Kind: PostActionLabel, Target: "Job Applications"}},
+		{in: "move: Jobs/Applied", want: PostAction{Kind: PostActionMove, Target: "Jobs/Applied"}},
+		{in: "label:", wantErr: true},
+		{in: "move", wantErr: true},
+		{in: "delete", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePostAction(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePostAction(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParsePostAction(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPostActionMarkRead(t *testing.T) {
+	addr, be := startTestIMAPServer(t)
+	c, err := dialTestIMAPServer(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Logout()
+
+	addTestEmail(t, be, "Your application was sent to Acme")
+	emails, _, err := FetchNewEmails(c, "INBOX", Checkpoint{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasTestFlag(t, c, emails[0].UID, imap.SeenFlag) {
+		t.Fatal("fetching the email marked it as read")
+	}
+
+	if err := (PostAction{Kind: PostActionMarkRead}).Apply(c, []uint32{emails[0].UID}); err != nil {
+		t.Fatal(err)
+	}
+	if !hasTestFlag(t, c, emails[0].UID, imap.SeenFlag) {
+		t.Error("email is not marked as read")
+	}
+}
+
+func hasTestFlag(t *testing.T, c *client.Client, uid uint32, flag string) bool {
+	t.Helper()
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uid)
+	messages := make(chan *imap.Message, 1)
+	if err := c.UidFetch(seqSet, []imap.FetchItem{imap.FetchFlags}, messages); err != nil {
+		t.Fatal(err)
+	}
+	msg := <-messages
+	if msg == nil {
+		t.Fatalf("message %d not found", uid)
+	}
+	return slices.Contains(msg.Flags, flag)
+}
+
+func TestParseEmailDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // UTC
+	}{
+		{"Mon, 02 Jan 2006 15:04:05 -0700", "2006-01-02 22:04:05"},
+		{"Tue, 7 Oct 2025 09:01:02 +0200 (CEST)", "2025-10-07 07:01:02"},
+		{"Tue,  7 Oct 2025 09:01:02 +0000 (Coordinated Universal Time)", "2025-10-07 09:01:02"},
+		{"7 Oct 2025 09:01:02 GMT", "2025-10-07 09:01:02"},
+		{"Tue, 7 Oct 2025 09:01 -0400", "2025-10-07 13:01:00"},
+		{"07 Oct 25 09:01:02 +0000", "2025-10-07 09:01:02"},
+		{"Tue Oct  7 09:01:02 2025", "2025-10-07 09:01:02"},
+		{"2025-10-07T09:01:02+02:00", "2025-10-07 07:01:02"},
+	}
+
+	for _, tt := range tests {
+		got, err := parseEmailDate(tt.in)
+		if err != nil {
+			t.Errorf("parseEmailDate(%q) error: %v", tt.in, err)
+			continue
+		}
+		if s := got.UTC().Format(time.DateTime); s != tt.want {
+			t.Errorf("parseEmailDate(%q) = %s, want %s", tt.in, s, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "yesterday"} {
+		if _, err := parseEmailDate(bad); err == nil {
+			t.Errorf("parseEmailDate(%q) should fail", bad)
+		}
+	}
+}
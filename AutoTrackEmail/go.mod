@@ -3,9 +3,15 @@ module autotrackemail
 go 1.24.4
 
 require (
-	github.com/emersion/go-imap v1.2.1 // indirect
-	github.com/emersion/go-message v0.18.2 // indirect
-	github.com/emersion/go-sasl v0.0.0-20200509203442-7bfe0ed36a21 // indirect
-	github.com/joho/godotenv v1.5.1 // indirect
-	golang.org/x/text v0.14.0 // indirect
+	github.com/emersion/go-imap v1.2.1
+	github.com/emersion/go-message v0.18.2
+	github.com/emersion/go-sasl v0.0.0-20200509203442-7bfe0ed36a21
+	github.com/joho/godotenv v1.5.1
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/net v0.44.0
+	golang.org/x/oauth2 v0.30.0
+	golang.org/x/text v0.29.0
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/sys v0.36.0 // indirect
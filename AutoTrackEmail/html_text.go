@@ -0,0 +1,187 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// elements whose content is never shown as text
+var hiddenElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Title:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Object:   true,
+	atom.Iframe:   true,
+}
+
+// elements that start and end a line
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Br: true, atom.Caption: true, atom.Center: true, atom.Dd: true, atom.Div: true,
+	atom.Dl: true, atom.Dt: true, atom.Footer: true, atom.Form: true, atom.H1: true,
+	atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Header: true, atom.Hr: true, atom.Li: true, atom.Main: true, atom.Nav: true,
+	atom.Ol: true, atom.P: true, atom.Pre: true, atom.Section: true, atom.Table: true,
+	atom.Tbody: true, atom.Tfoot: true, atom.Thead: true, atom.Tr: true, atom.Ul: true,
+}
+
+// htmlToText renders an HTML body as text for the parsers: one line per block element or
+// table row, table cells separated by tabs, entities decoded and hidden elements such as
+// <style> and <script> dropped. It also returns the http(s) targets of the links, since job
+// URLs are often only in anchors
+func htmlToText(src string) (string, []string) {
+	z := html.NewTokenizer(strings.NewReader(src))
+	var w textWriter
+	var links []string
+	seen := map[string]bool{}
+
+	var hidden atom.Atom // hidden element being skipped, nested ones of the same kind are counted
+	hiddenDepth := 0
+	pre := 0
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken: // io.EOF, the tokenizer gets through any malformed markup
+			return w.String(), links
+
+		case html.TextToken:
+			if hiddenDepth == 0 {
+				w.text(string(z.Text()), pre > 0)
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			a := atom.Lookup(name)
+			if hiddenDepth > 0 {
+				if a == hidden && tt == html.StartTagToken {
+					hiddenDepth++
+				}
+				continue
+			}
+
+			switch {
+			case hiddenElements[a]:
+				if tt == html.StartTagToken {
+					hidden, hiddenDepth = a, 1
+				}
+			case a == atom.Td || a == atom.Th:
+				w.cell()
+			case blockElements[a]:
+				w.lineBreak()
+				if a == atom.Pre && tt == html.StartTagToken {
+					pre++
+				}
+			case a == atom.A && hasAttr:
+				for {
+					key, val, more := z.TagAttr()
+					if href := strings.TrimSpace(string(val)); string(key) == "href" && isWebLink(href) && !seen[href] {
+						seen[href] = true
+						links = append(links, href)
+					}
+					if !more {
+						break
+					}
+				}
+			}
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			a := atom.Lookup(name)
+			if hiddenDepth > 0 {
+				if a == hidden {
+					hiddenDepth--
+				}
+				continue
+			}
+			if blockElements[a] {
+				w.lineBreak()
+				if a == atom.Pre && pre > 0 {
+					pre--
+				}
+			}
+		}
+	}
+}
+
+func isWebLink(href string) bool {
+	href = strings.ToLower(href)
+	return strings.HasPrefix(href, "https://") || strings.HasPrefix(href, "http://")
+}
+
+// textWriter collapses whitespace like a browser and drops empty lines
+type textWriter struct {
+	lines []string
+	line  strings.Builder
+	sep   string // written before the next word of the line
+}
+
+// invisible characters used by email templates to pad the preview text
+var invisibleChars = strings.NewReplacer("\u200b", "", "\u200c", "", "\u200d", "", "\ufeff", "", "\u00a0", " ")
+
+func (w *textWriter) text(s string, pre bool) {
+	s = invisibleChars.Replace(s)
+	if pre {
+		for i, line := range strings.Split(s, "\n") {
+			if i > 0 {
+				w.lineBreak()
+			}
+			w.word(strings.TrimRight(line, " \t\r"))
+		}
+		return
+	}
+
+	if s != "" && isSpace(s[0]) {
+		w.space()
+	}
+	for _, word := range strings.Fields(s) {
+		w.word(word)
+		w.space()
+	}
+	if s != "" && !isSpace(s[len(s)-1]) {
+		w.sep = ""
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func (w *textWriter) word(s string) {
+	if s == "" {
+		return
+	}
+	if w.line.Len() > 0 {
+		w.line.WriteString(w.sep)
+	}
+	w.line.WriteString(s)
+	w.sep = ""
+}
+
+func (w *textWriter) space() {
+	if w.sep == "" {
+		w.sep = " "
+	}
+}
+
+func (w *textWriter) cell() {
+	w.sep = "\t"
+}
+
+func (w *textWriter) lineBreak() {
+	if line := strings.TrimSpace(w.line.String()); line != "" {
+		w.lines = append(w.lines, line)
+	}
+	w.line.Reset()
+	w.sep = ""
+}
+
+func (w *textWriter) String() string {
+	w.lineBreak()
+	return strings.Join(w.lines, "\n")
+}
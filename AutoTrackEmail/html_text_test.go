@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"paragraphs", "<p>Hi Sam,</p><p>Thanks for applying.</p>", "Hi Sam,\nThanks for applying."},
+		{"line breaks", "Line one<br>Line two<br/>Line three", "Line one\nLine two\nLine three"},
+		{"multi-line tag", "<p\n  class=\"x\"\n  style=\"margin:0\">Data Engineer</p>", "Data Engineer"},
+		{"entities", "<p>Globex &amp; Partners &ndash; R&#38;D &lt;team&gt;</p>", "Globex & Partners – R&D <team>"},
+		{"whitespace collapsed", "<p>  Site\n   Reliability&nbsp;&nbsp;Engineer </p>", "Site Reliability Engineer"},
+		{"inline elements", "<p>Applied to <b>Acme</b>, <i>Backend</i>Developer</p>", "Applied to Acme, BackendDeveloper"},
+		{"hidden elements", "<head><title>T</title><style>p { color: red }</style></head><p>Text</p><script>var a = '<p>x</p>'</script><noscript>Enable JS</noscript>", "Text"},
+		{"preview padding", "<div style=\"display:none\">&zwnj;&nbsp;&zwnj;&#8203;</div><p>Hello</p>", "Hello"},
+		{"comments", "<p>Hello<!-- <p>hidden</p> --> world</p>", "Hello world"},
+		{"table rows and cells", "<table><tr><td>Job</td><td>Data Engineer</td></tr><tr><th>Location</th><td>Remote</td></tr></table>", "Job\tData Engineer\nLocation\tRemote"},
+		{"nested tables", "<table><tr><td><table><tr><td>Inner</td></tr></table></td><td>Outer</td></tr></table>", "Inner\nOuter"},
+		{"lists", "<ul><li>One</li><li>Two</li></ul>", "One\nTwo"},
+		{"pre", "<pre>Title:   Engineer\nTeam:    Platform</pre>", "Title:   Engineer\nTeam:    Platform"},
+		{"unclosed tags", "<div><p>Thanks for applying<p>to Initech", "Thanks for applying\nto Initech"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := htmlToText(tt.html)
+			if got != tt.want {
+				t.Errorf("htmlToText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLToTextLinks(t *testing.T) {
+	_, links := htmlToText(`<p><a href=" https://jobs.lever.co/initech/123 ">Job posting</a></p>
+		<a href="mailto:jobs@initech.example">Email us</a> <a href="#top">Top</a>
+		<a class="btn" href="https://jobs.lever.co/initech/123">Apply</a>
+		<a href="http://initech.example/careers?ref=email&amp;src=ats">Careers</a>`)
+
+	want := []string{"https://jobs.lever.co/initech/123", "http://initech.example/careers?ref=email&src=ats"}
+	if !slices.Equal(links, want) {
+		t.Errorf("links = %q, want %q", links, want)
+	}
+}
+
+func TestReadEmailConvertsHTMLBody(t *testing.T) {
+	f, err := os.Open("testdata/emails/workday_html_table.eml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	email, err := readEmail(f, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(email.Body, "Job\tR-10233 Site Reliability Engineer\nLocation\tAustin, TX") {
+		t.Errorf("table rows missing from body:\n%s", email.Body)
+	}
+	if strings.ContainsAny(email.Body, "<>{}") {
+		t.Errorf("markup or styles left in body:\n%s", email.Body)
+	}
+	want := []string{"https://globex.wd5.myworkdayjobs.com/en-US/careers/job/R-10233"}
+	if !slices.Equal(email.Links, want) {
+		t.Errorf("links = %q, want %q", email.Links, want)
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-sasl"
+	"golang.org/x/oauth2"
+)
+
+// IMAP_AUTH values
+const (
+	AuthPassword    = "password"    // LOGIN with EMAIL_PASSWORD, e.g. a Gmail App Password
+	AuthXOAuth2     = "xoauth2"     // SASL XOAUTH2, used by Gmail and Outlook
+	AuthOAuthBearer = "oauthbearer" // SASL OAUTHBEARER (RFC 7628)
+)
+
+// IMAPAuth logs in on a new connection
+type IMAPAuth func(c *client.Client) error
+
+// IMAPAuthFromEnv picks the login method from IMAP_AUTH (password by default)
+func IMAPAuthFromEnv(cfg IMAPConfig, username, password string) (IMAPAuth, error) {
+	switch method := strings.ToLower(os.Getenv("IMAP_AUTH")); method {
+	case "", AuthPassword:
+		if password == "" {
+			return nil, errors.New("please set EMAIL_PASSWORD in .env file, or IMAP_AUTH=xoauth2")
+		}
+		return PasswordAuth(username, password), nil
+	case AuthXOAuth2, AuthOAuthBearer:
+		oauthConfig, err := OAuthConfigFromEnv(cfg.Host)
+		if err != nil {
+			return nil, err
+		}
+		tokens, err := NewTokenSource(context.Background(), oauthConfig, tokenFileFromEnv())
+		if err != nil {
+			return nil, err
+		}
+		return OAuthAuth(method, username, tokens), nil
+	default:
+		return nil, fmt.Errorf("invalid IMAP_AUTH %q, use password, xoauth2 or oauthbearer", method)
+	}
+}
+
+func PasswordAuth(username, password string) IMAPAuth {
+	return func(c *client.Client) error {
+		return c.Login(username, password)
+	}
+}
+
+// OAuthAuth authenticates with SASL XOAUTH2 or OAUTHBEARER, refreshing the access token when it expired
+func OAuthAuth(mechanism, username string, tokens oauth2.TokenSource) IMAPAuth {
+	return func(c *client.Client) error {
+		name := strings.ToUpper(mechanism)
+		if ok, err := c.SupportAuth(name); err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("server does not support AUTH=%s", name)
+		}
+
+		tok, err := tokens.Token()
+		if err != nil {
+			return fmt.Errorf("getting OAuth access token: %w", err)
+		}
+
+		if mechanism == AuthXOAuth2 {
+			return c.Authenticate(&xoauth2Client{username: username, token: tok.AccessToken})
+		}
+		return c.Authenticate(oauthBearerClient{sasl.NewOAuthBearerClient(&sasl.OAuthBearerOptions{Username: username, Token: tok.AccessToken})})
+	}
+}
+
+// XOAUTH2 is the pre-standard form of OAUTHBEARER that Google and Microsoft still use
+type xoauth2Client struct {
+	username string
+	token    string
+}
+
+func (a *xoauth2Client) Start() (mech string, ir []byte, err error) {
+	return "XOAUTH2", []byte("user=" + a.username + "\x01auth=Bearer " + a.token + "\x01\x01"), nil
+}
+
+// on failure the server sends a JSON error as challenge and expects an empty response before its NO
+func (a *xoauth2Client) Next(challenge []byte) ([]byte, error) {
+	return []byte{}, nil
+}
+
+// go-sasl's OAUTHBEARER client cancels on the error challenge and the exchange never ends,
+// RFC 7628 wants a 0x01 response so the server can answer with NO
+type oauthBearerClient struct {
+	sasl.Client
+}
+
+func (a oauthBearerClient) Next(challenge []byte) ([]byte, error) {
+	return []byte{0x01}, nil
+}
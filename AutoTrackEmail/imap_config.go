@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/emersion/go-imap/client"
+)
+
+// how the connection to the IMAP server is secured
+const (
+	SecurityTLS      = "tls"      // implicit TLS, usually port 993
+	SecurityStartTLS = "starttls" // plain connection upgraded with STARTTLS, usually port 143
+	SecurityNone     = "none"     // no encryption, only for local servers
+)
+
+// IMAPConfig says where the mailbox lives
+type IMAPConfig struct {
+	Host     string
+	Port     int
+	Security string
+	Mailbox  string // mailbox to watch, e.g. INBOX
+}
+
+// settings of common providers, picked with IMAP_PROVIDER
+var providerPresets = map[string]IMAPConfig{
+	"gmail":    {Host: "imap.gmail.com", Port: 993, Security: SecurityTLS, Mailbox: "INBOX"},
+	"outlook":  {Host: "outlook.office365.com", Port: 993, Security: SecurityTLS, Mailbox: "INBOX"},
+	"fastmail": {Host: "imap.fastmail.com", Port: 993, Security: SecurityTLS, Mailbox: "INBOX"},
+	"yahoo":    {Host: "imap.mail.yahoo.com", Port: 993, Security: SecurityTLS, Mailbox: "INBOX"},
+	"icloud":   {Host: "imap.mail.me.com", Port: 993, Security: SecurityTLS, Mailbox: "INBOX"},
+}
+
+// IMAPConfigFromEnv starts from the IMAP_PROVIDER preset (gmail if neither it nor IMAP_HOST is set)
+// and applies IMAP_HOST, IMAP_PORT, IMAP_SECURITY and IMAP_MAILBOX on top of it
+func IMAPConfigFromEnv() (IMAPConfig, error) {
+	provider := strings.ToLower(os.Getenv("IMAP_PROVIDER"))
+	if provider == "" && os.Getenv("IMAP_HOST") == "" {
+		provider = "gmail"
+	}
+
+	cfg := IMAPConfig{Security: SecurityTLS, Mailbox: "INBOX"}
+	if provider != "" {
+		preset, ok := providerPresets[provider]
+		if !ok {
+			return IMAPConfig{}, fmt.Errorf("unknown IMAP_PROVIDER %q, use one of %s or set IMAP_HOST", provider, providerNames())
+		}
+		cfg = preset
+	}
+
+	if host := os.Getenv("IMAP_HOST"); host != "" {
+		cfg.Host = host
+	}
+	if security := os.Getenv("IMAP_SECURITY"); security != "" {
+		cfg.Security = strings.ToLower(security)
+	}
+	if mailbox := os.Getenv("IMAP_MAILBOX"); mailbox != "" {
+		cfg.Mailbox = mailbox
+	}
+
+	switch cfg.Security {
+	case SecurityTLS:
+		if cfg.Port == 0 {
+			cfg.Port = 993
+		}
+	case SecurityStartTLS, SecurityNone:
+		if cfg.Port == 0 || cfg.Port == 993 {
+			cfg.Port = 143 // the preset port is for implicit TLS
+		}
+	default:
+		return IMAPConfig{}, fmt.Errorf("invalid IMAP_SECURITY %q, use tls, starttls or none", cfg.Security)
+	}
+
+	if port := os.Getenv("IMAP_PORT"); port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil || p <= 0 || p > 65535 {
+			return IMAPConfig{}, fmt.Errorf("invalid IMAP_PORT %q", port)
+		}
+		cfg.Port = p
+	}
+
+	return cfg, nil
+}
+
+func (cfg IMAPConfig) Addr() string {
+	return net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+}
+
+// ConnectIMAP opens a connection secured as configured and logs in with auth
+func ConnectIMAP(cfg IMAPConfig, auth IMAPAuth) (*client.Client, error) {
+	c, err := dialIMAP(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = auth(c); err != nil {
+		c.Logout()
+		return nil, err
+	}
+
+	log.Printf("Successfully logged in to %s", cfg.Host)
+	return c, nil
+}
+
+func dialIMAP(cfg IMAPConfig) (*client.Client, error) {
+	switch cfg.Security {
+	case SecurityTLS:
+		return client.DialTLS(cfg.Addr(), nil)
+	case SecurityStartTLS:
+		c, err := client.Dial(cfg.Addr())
+		if err != nil {
+			return nil, err
+		}
+		if err := c.StartTLS(&tls.Config{ServerName: cfg.Host}); err != nil {
+			c.Logout()
+			return nil, fmt.Errorf("STARTTLS: %w", err)
+		}
+		return c, nil
+	case SecurityNone:
+		return client.Dial(cfg.Addr())
+	}
+	return nil, fmt.Errorf("invalid IMAP security %q", cfg.Security)
+}
+
+func providerNames() string {
+	names := make([]string, 0, len(providerPresets))
+	for name := range providerPresets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
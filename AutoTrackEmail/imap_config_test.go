@@ -0,0 +1,68 @@
+package main
+
+import "testing"
+
+func TestIMAPConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    IMAPConfig
+		wantErr bool
+	}{
+		{
+			name: "gmail by default",
+			want: IMAPConfig{Host: "imap.gmail.com", Port: 993, Security: SecurityTLS, Mailbox: "INBOX"},
+		},
+		{
+			name: "preset",
+			env:  map[string]string{"IMAP_PROVIDER": "Outlook"},
+			want: IMAPConfig{Host: "outlook.office365.com", Port: 993, Security: SecurityTLS, Mailbox: "INBOX"},
+		},
+		{
+			name: "preset with another mailbox",
+			env:  map[string]string{"IMAP_PROVIDER": "fastmail", "IMAP_MAILBOX": "Jobs"},
+			want: IMAPConfig{Host: "imap.fastmail.com", Port: 993, Security: SecurityTLS, Mailbox: "Jobs"},
+		},
+		{
+			name: "self hosted with starttls",
+			env:  map[string]string{"IMAP_HOST": "mail.example.com", "IMAP_SECURITY": "STARTTLS"},
+			want: IMAPConfig{Host: "mail.example.com", Port: 143, Security: SecurityStartTLS, Mailbox: "INBOX"},
+		},
+		{
+			name: "self hosted with port",
+			env:  map[string]string{"IMAP_HOST": "localhost", "IMAP_PORT": "1143", "IMAP_SECURITY": "none"},
+			want: IMAPConfig{Host: "localhost", Port: 1143, Security: SecurityNone, Mailbox: "INBOX"},
+		},
+		{
+			name:    "unknown provider",
+			env:     map[string]string{"IMAP_PROVIDER": "hotmail2000"},
+			wantErr: true,
+		},
+		{
+			name:    "bad port",
+			env:     map[string]string{"IMAP_HOST": "localhost", "IMAP_PORT": "imap"},
+			wantErr: true,
+		},
+		{
+			name:    "bad security",
+			env:     map[string]string{"IMAP_HOST": "localhost", "IMAP_SECURITY": "ssl3"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"IMAP_PROVIDER", "IMAP_HOST", "IMAP_PORT", "IMAP_SECURITY", "IMAP_MAILBOX"} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			got, err := IMAPConfigFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ImportFilter picks the emails of an offline import
+type ImportFilter struct {
+	Since   string   // YYYY-MM-DD, inclusive, empty for no lower bound
+	Until   string   // YYYY-MM-DD, inclusive, empty for no upper bound
+	Senders []string // addresses ("jobs@acme.com") or domains ("acme.com", subdomains match too), empty for anyone
+	All     bool     // also emails the job keyword filter would skip
+}
+
+// Match reports whether the email is in the date range, from one of the senders and job related
+func (f ImportFilter) Match(email EmailData) bool {
+	if (f.Since != "" && email.Date < f.Since) || (f.Until != "" && email.Date > f.Until) {
+		return false
+	}
+	if len(f.Senders) > 0 && !f.fromSender(email.From) {
+		return false
+	}
+	return f.All || isJobEmail(email.From, email.Subject)
+}
+
+func (f ImportFilter) fromSender(from string) bool {
+	from = strings.ToLower(from)
+	domain := addressDomain(from)
+	for _, sender := range f.Senders {
+		sender = strings.ToLower(strings.TrimSpace(sender))
+		if strings.Contains(sender, "@") {
+			if from == sender {
+				return true
+			}
+		} else if domain == sender || strings.HasSuffix(domain, "."+sender) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadEmails calls fn with every email of path, which is a .eml file, an mbox archive (a Google
+// Takeout export for instance), a Maildir or a folder of those. source names the email in logs,
+// "archive.mbox#3" for the third email of an archive. Emails that cannot be parsed are logged and skipped
+func ReadEmails(path string, fn func(source string, email EmailData) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return readEmailSource(path, info, fn)
+	}
+
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if isMaildir(p) {
+				return readMaildir(p, fn)
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".eml", ".mbox":
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return readEmailSource(p, info, fn)
+		}
+		return nil
+	})
+}
+
+// a single .eml file or mbox archive, mbox archives start with a "From " line
+func readEmailSource(path string, info fs.FileInfo, fn func(source string, email EmailData) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	start, _ := r.Peek(5)
+	if strings.EqualFold(filepath.Ext(path), ".mbox") || string(start) == "From " {
+		return readMbox(r, path, info.ModTime(), fn)
+	}
+	return emitEmail(path, r, info.ModTime(), fn)
+}
+
+// a Maildir has cur and new subfolders, tmp only holds emails being delivered
+func isMaildir(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, "cur"))
+	return err == nil && info.IsDir()
+}
+
+// read the delivered emails of a Maildir, its Maildir++ subfolders (.Jobs/cur) are reached by the walk
+func readMaildir(dir string, fn func(source string, email EmailData) error) error {
+	for _, sub := range []string{"new", "cur"} {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		// file names start with the delivery time, so this is oldest first
+		for _, entry := range entries {
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			path := filepath.Join(dir, sub, entry.Name())
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			err = emitEmail(path, f, info.ModTime(), fn)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readMbox splits an mbox archive on its "From " lines and undoes the ">From " quoting of mboxrd.
+// received is used for emails without a Date header when their "From " line has no date either
+func readMbox(r io.Reader, path string, received time.Time, fn func(source string, email EmailData) error) error {
+	br := bufio.NewReader(r)
+	var msg bytes.Buffer
+	count := 0
+	msgReceived := received
+	inMessage, afterBlank := false, true
+
+	flush := func() error {
+		if !inMessage {
+			return nil
+		}
+		count++
+		return emitEmail(fmt.Sprintf("%s#%d", path, count), &msg, msgReceived, fn)
+	}
+
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if afterBlank && bytes.HasPrefix(line, []byte("From ")) {
+				if err := flush(); err != nil {
+					return err
+				}
+				msg.Reset()
+				inMessage = true
+				msgReceived = fromLineDate(line, received)
+			} else if inMessage {
+				if quoted := bytes.TrimLeft(line, ">"); len(quoted) < len(line) && bytes.HasPrefix(quoted, []byte("From ")) {
+					line = line[1:] // ">>From " -> ">From "
+				}
+				msg.Write(line)
+			}
+			afterBlank = len(bytes.TrimRight(line, "\r\n")) == 0
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return flush()
+}
+
+// date of an mbox separator, "From 1234@xxx Tue Oct 07 14:02:11 +0000 2025" or "From jane@acme.com Tue Oct  7 14:02:11 2025"
+func fromLineDate(line []byte, fallback time.Time) time.Time {
+	fields := strings.Fields(string(line))
+	if len(fields) < 3 {
+		return fallback
+	}
+	value := strings.Join(fields[2:], " ")
+	for _, layout := range []string{"Mon Jan 2 15:04:05 2006", "Mon Jan 2 15:04:05 -0700 2006"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return fallback
+}
+
+func emitEmail(source string, r io.Reader, received time.Time, fn func(source string, email EmailData) error) error {
+	email, err := readEmail(r, received)
+	if err != nil {
+		log.Printf("Skipping %s: %v", source, err)
+		return nil
+	}
+	return fn(source, email)
+}
+
+// ImportStats counts what an import went through
+type ImportStats struct {
+	Read    int // emails read
+	Matched int // emails that passed the filter
+	Jobs    int // jobs parsed from those
+}
+
+// ImportEmails parses the job emails of the paths (see ReadEmails) that pass the filter and hands each job to handle
+func ImportEmails(paths []string, filter ImportFilter, handle func(source string, email EmailData, job Job) error) (ImportStats, error) {
+	var stats ImportStats
+	for _, path := range paths {
+		err := ReadEmails(path, func(source string, email EmailData) error {
+			stats.Read++
+			if !filter.Match(email) {
+				return nil
+			}
+			stats.Matched++
+
+			job := ParseJobFromEmail(email)
+			if job == nil {
+				log.Printf("No job found in %s: %s", source, email.Subject)
+				return nil
+			}
+			stats.Jobs++
+			return handle(source, email, *job)
+		})
+		if err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func fixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "emails", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// reads path and returns "source: subject (date)" for every email
+func readAll(t *testing.T, path string) []string {
+	t.Helper()
+	var got []string
+	err := ReadEmails(path, func(source string, email EmailData) error {
+		source, _ = filepath.Rel(filepath.Dir(path), source)
+		got = append(got, source+": "+email.Subject+" ("+email.Date+")")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestReadEmailsMbox(t *testing.T) {
+	newsletter := "From: news@example.com\nSubject: Weekly news\nDate: Mon, 6 Oct 2025 08:00:00 +0000\n\nHello\n\n>From the editor: nothing about jobs\n>>From quoted twice\n"
+	mbox := "From 1846183@xxx Thu Oct 09 11:12:13 +0000 2025\n" + fixture(t, "lever_html_only.eml") + "\n" +
+		"From news@example.com Mon Oct  6 08:00:00 2025\n" + newsletter + "\n" +
+		"From 1846190@xxx Sun Oct 12 07:30:00 +0000 2025\n" + fixture(t, "missing_date.eml") + "\n"
+	path := filepath.Join(t.TempDir(), "takeout.mbox")
+	writeFile(t, path, mbox)
+
+	got := readAll(t, path)
+	want := []string{
+		"takeout.mbox#1: Thank you for your application to Initech (2025-10-09)",
+		"takeout.mbox#2: Weekly news (2025-10-06)",
+		"takeout.mbox#3: Application received - Data Scientist position (2025-10-12)", // date of the "From " line
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	var body string
+	ReadEmails(path, func(source string, email EmailData) error {
+		if email.Subject == "Weekly news" {
+			body = email.Body
+		}
+		return nil
+	})
+	if !strings.Contains(body, "\nFrom the editor") || !strings.Contains(body, "\n>From quoted twice") {
+		t.Errorf("quoted From lines not restored:\n%s", body)
+	}
+}
+
+func TestReadEmailsMaildirAndFolders(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "Maildir", "cur", "1760000000.M1P1.host:2,S"), fixture(t, "workday_received.eml"))
+	writeFile(t, filepath.Join(dir, "Maildir", "new", "1760100000.M2P1.host"), fixture(t, "ashby_thanks.eml"))
+	writeFile(t, filepath.Join(dir, "Maildir", "tmp", "1760200000.M3P1.host"), "From: half@delivered\n")
+	writeFile(t, filepath.Join(dir, "Maildir", ".Jobs", "cur", "1760000001.M4P1.host:2,"), fixture(t, "icims_base64.eml"))
+	writeFile(t, filepath.Join(dir, "saved", "debug.eml"), fixture(t, "generic_interview.eml"))
+	writeFile(t, filepath.Join(dir, "saved", "notes.txt"), "not an email")
+
+	got := readAll(t, dir)
+	name := filepath.Base(dir)
+	want := []string{
+		name + "/Maildir/new/1760100000.M2P1.host: Thanks for applying to Pied Piper! (2025-10-15)",
+		name + "/Maildir/cur/1760000000.M1P1.host:2,S: Application Received: Frontend Developer (R-10422) (2025-10-10)",
+		name + "/Maildir/.Jobs/cur/1760000001.M4P1.host:2,: Thank you for applying for the Product Manager position (2025-10-13)",
+		name + "/saved/debug.eml: Next steps: interview for the Security Analyst role (2025-10-17)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestImportEmailsFilters(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"lever_html_only.eml", "workday_received.eml", "icims_base64.eml", "generic_interview.eml"} {
+		writeFile(t, filepath.Join(dir, name), fixture(t, name))
+	}
+	writeFile(t, filepath.Join(dir, "newsletter.eml"), "From: news@myworkday.com\nSubject: Weekly news\nDate: Mon, 13 Oct 2025 08:00:00 +0000\n\nHello\n")
+
+	tests := []struct {
+		name        string
+		filter      ImportFilter
+		wantMatched int
+		want        string
+	}{
+		{"job emails", ImportFilter{}, 4, "Wayne Enterprises, Hooli, Initech, Umbrella Corporation"},
+		{"date range", ImportFilter{Since: "2025-10-10", Until: "2025-10-13"}, 2, "Hooli, Umbrella Corporation"},
+		{"sender domain", ImportFilter{Senders: []string{"icims.com", " MyWorkday.com"}}, 2, "Hooli, Umbrella Corporation"},
+		{"sender address", ImportFilter{Senders: []string{"jane@wayne.example"}}, 1, "Wayne Enterprises"},
+		{"any subject", ImportFilter{Senders: []string{"myworkday.com"}, All: true}, 2, "News, Umbrella Corporation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var companies []string
+			stats, err := ImportEmails([]string{dir}, tt.filter, func(source string, email EmailData, job Job) error {
+				companies = append(companies, job.Company)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(companies, ", "); got != tt.want {
+				t.Errorf("jobs = %s, want %s", got, tt.want)
+			}
+			if stats.Read != 5 || stats.Matched != tt.wantMatched || stats.Jobs != len(companies) {
+				t.Errorf("stats = %+v", stats)
+			}
+		})
+	}
+}
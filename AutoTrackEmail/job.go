@@ -1,9 +1,50 @@
 package main
 
 type Job struct {
-	Company     string `json:"company"` //company name
-	Title       string `json:"title"`   //job title
-	Status      string `json:"status"`  // applied,interview,offer,rejected
-	AppliedDate string `json:"applied_date"`
-	Notes       string `json:"notes"`
+	Company         string     `json:"company"` //company name
+	Title           string     `json:"title"`   //job title
+	Location        string     `json:"location,omitempty"`
+	Status          string     `json:"status"` // one of JobStatuses
+	AppliedDate     string     `json:"applied_date"`
+	Notes           string     `json:"notes"`
+	SourceMessageID string     `json:"source_message_id,omitempty"` // Message-ID of the email, lets the server spot duplicates
+	Confidence      Confidence `json:"-"`                           // how sure the parser is, low confidence jobs go to the review queue
+	Sources         Sources    `json:"-"`                           // what found each field, shown by --explain
+}
+
+// statuses the server accepts, replaced with the server's list (GET /statuses) when the watcher starts
+var JobStatuses = []string{"applied", "interview", "offer", "rejected"}
+
+// set JobStatuses
+func SetJobStatuses(statuses []string) {
+	JobStatuses = statuses
+}
+
+// Confidence of each extracted field, from 0 (not found) to 1 (certain)
+type Confidence struct {
+	Company float64 `json:"company"`
+	Title   float64 `json:"title"`
+	Status  float64 `json:"status"`
+}
+
+// Sources names the parser or rule and the pattern that produced each field,
+// e.g. "lever subject /(?i)application to\s+(?P<company>.+)/"
+type Sources struct {
+	Company string `json:"company,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Status  string `json:"status,omitempty"`
+}
+
+// confidence of the extraction methods, the generic heuristics are split between
+// phrasings that announce the company or title and looser patterns that often misfire
+const (
+	confidenceRule         = 1.0 // written by the user in the rules file or the company mapping
+	confidenceATS          = 0.9 // pattern of a dedicated ATS parser
+	confidenceGeneric      = 0.6 // "thank you for applying to X", "for the X position"
+	confidenceGenericLoose = 0.3 // "at X,", "X team", a known title somewhere in the subject
+)
+
+// Min is the confidence of the least certain field
+func (c Confidence) Min() float64 {
+	return min(c.Company, c.Title, c.Status)
 }
@@ -1,15 +1,28 @@
 package main
 
 import (
+	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 )
 
+// genericParser is the fallback for senders without a dedicated parser, it tries common phrasings
+type genericParser struct{}
+
+func (genericParser) Name() string {
+	return "generic"
+}
+
+func (genericParser) Match(email EmailData) bool {
+	return true
+}
+
 // Extract job info from email data
-func ParseJobFromEmail(email EmailData) *Job {
-	company := extractCompanyFromEmail(email.Subject, email.Body)
-	title := extractTitleFromEmail(email.Subject, email.Body)
-	status := determineJobStatus(email.Subject)
+func (genericParser) Parse(email EmailData) *Job {
+	company, companyConfidence, companySource := extractCompanyFromEmail(email.Subject, email.Body)
+	title, titleConfidence, titleSource := extractTitleFromEmail(email.Subject, email.Body)
+	status, statusConfidence, statusSource := statusFromSubject(email.Subject)
 
 	// Skip if cant find data
 	if company == "" && title == "" {
@@ -17,32 +30,35 @@ func ParseJobFromEmail(email EmailData) *Job {
 	}
 
 	return &Job{
-		Company:     company,
-		Title:       title,
-		Status:      status,
-		AppliedDate: email.Date,
-		Notes:       email.Subject,
+		Company:         company,
+		Title:           title,
+		Status:          status,
+		AppliedDate:     email.Date,
+		Notes:           email.Subject,
+		SourceMessageID: email.MessageID,
+		Confidence:      Confidence{Company: companyConfidence, Title: titleConfidence, Status: statusConfidence},
+		Sources:         Sources{Company: parserSource("generic", companySource), Title: parserSource("generic", titleSource), Status: parserSource("generic", statusSource)},
 	}
 }
 
-// extract company info
-func extractCompanyFromEmail(subject, body string) string {
+// extract company info, how sure the pattern that found it is and which pattern it was
+func extractCompanyFromEmail(subject, body string) (string, float64, string) {
 	//First try subject
-	company := extractCompanyFromSubject(subject)
+	company, confidence, source := extractCompanyFromSubject(subject)
 	if company != "Unknown Company" {
-		return company
+		return company, confidence, source
 	}
 
 	//then try body
 	return extractCompanyFromBody(body)
 }
 
-// extract title
-func extractTitleFromEmail(subject, body string) string {
+// extract title, how sure the pattern that found it is and which pattern it was
+func extractTitleFromEmail(subject, body string) (string, float64, string) {
 	//First try subject
-	title := extractTitleFromSubject(subject)
+	title, confidence, source := extractTitleFromSubject(subject)
 	if title != "Unknown Position" {
-		return title
+		return title, confidence, source
 	}
 
 	//then try body
@@ -50,26 +66,21 @@ func extractTitleFromEmail(subject, body string) string {
 }
 
 // extract company from subject
-func extractCompanyFromSubject(subject string) string {
-	// LinkedIn-specific patterns first
-	linkedInPatterns := []string{
-		`(?i)your application was sent to\s+(.+?)(?:\s*$|\s*\|)`,
-		`(?i)application sent.*?at\s+(.+?)(?:\s*$|\s*\|)`,
+func extractCompanyFromSubject(subject string) (string, float64, string) {
+	// Application confirmation phrasings first
+	applicationPatterns := []string{
 		`(?i)(.+?):\s*your application`,
 		`(?i)application.*?to\s+(.+?)(?:\s+for|\s*$)`,
-		`(?i)thank you for applying to\s+(.+?)(?:\s+for|\s*$)`,
+		`(?i)thank(?:s| you) for applying to\s+(.+?)(?:\s+for|\s*!?$)`,
 	}
 
-	for _, pattern := range linkedInPatterns {
+	for _, pattern := range applicationPatterns {
 		re := regexp.MustCompile(pattern)
 		matches := re.FindStringSubmatch(subject)
 		if len(matches) > 1 {
 			company := strings.TrimSpace(matches[1])
-			// Clean up common suffixes
-			company = strings.TrimSuffix(company, " - LinkedIn")
-			company = strings.TrimSuffix(company, " via LinkedIn")
 			if len(company) > 1 && len(company) < 100 {
-				return company
+				return company, confidenceGeneric, patternSource("subject", pattern)
 			}
 		}
 	}
@@ -88,7 +99,7 @@ func extractCompanyFromSubject(subject string) string {
 		if len(matches) > 1 {
 			company := strings.TrimSpace(matches[1])
 			if len(company) > 1 && len(company) < 50 {
-				return company
+				return company, confidenceGenericLoose, patternSource("subject", pattern)
 			}
 		}
 	}
@@ -99,18 +110,18 @@ func extractCompanyFromSubject(subject string) string {
 		words := strings.Fields(subject)
 		for i, word := range words {
 			if strings.ToLower(word) == "team" && i > 0 {
-				return words[i-1]
+				return words[i-1], confidenceGenericLoose, `subject word before "team"`
 			}
 		}
 	}
 
-	return "Unknown Company"
+	return "Unknown Company", 0, ""
 }
 
 // extract company from body
-func extractCompanyFromBody(body string) string {
+func extractCompanyFromBody(body string) (string, float64, string) {
 	if body == "" {
-		return "Unknown Company"
+		return "Unknown Company", 0, ""
 	}
 
 	lines := strings.Split(body, "\n")
@@ -131,34 +142,40 @@ func extractCompanyFromBody(body string) string {
 			if len(matches) > 1 {
 				company := strings.TrimSpace(matches[1])
 				if len(company) > 1 && len(company) < 50 {
-					return company
+					return company, confidenceGenericLoose, patternSource("body", pattern)
 				}
 			}
 		}
 	}
 
-	return "Unknown Company"
+	return "Unknown Company", 0, ""
+}
+
+// a generic pattern and how much what it captures can be trusted
+type scoredPattern struct {
+	expr       string
+	confidence float64
 }
 
 // extract title from subject
-func extractTitleFromSubject(subject string) string {
+func extractTitleFromSubject(subject string) (string, float64, string) {
 
 	// Common patterns for job titles
-	patterns := []string{
-		`(?i)for\s+the\s+([^-]+?)(?:\s+position|\s+role)`,
-		`(?i)position:\s+([^-\n]+?)(?:\s+at|\s+-|$)`,
-		`(?i)role:\s+([^-\n]+?)(?:\s+at|\s+-|$)`,
-		`(?i)([A-Za-z\s]+?)\s+position`,
-		`(?i)([A-Za-z\s]+?)\s+role`,
+	patterns := []scoredPattern{
+		{`(?i)for\s+the\s+([^-]+?)(?:\s+position|\s+role)`, confidenceGeneric},
+		{`(?i)position:\s+([^-\n]+?)(?:\s+at|\s+-|$)`, confidenceGeneric},
+		{`(?i)role:\s+([^-\n]+?)(?:\s+at|\s+-|$)`, confidenceGeneric},
+		{`(?i)([A-Za-z\s]+?)\s+position`, 0.5},
+		{`(?i)([A-Za-z\s]+?)\s+role`, 0.4}, // "your role", "next steps in the role"
 	}
 
 	for _, pattern := range patterns {
-		re := regexp.MustCompile(pattern)
+		re := regexp.MustCompile(pattern.expr)
 		matches := re.FindStringSubmatch(subject)
 		if len(matches) > 1 {
 			title := strings.TrimSpace(matches[1])
 			if len(title) > 1 && len(title) < 100 {
-				return title
+				return title, pattern.confidence, patternSource("subject", pattern.expr)
 			}
 		}
 	}
@@ -174,136 +191,79 @@ func extractTitleFromSubject(subject string) string {
 	subjectLower := strings.ToLower(subject)
 	for _, title := range commonTitles {
 		if strings.Contains(subjectLower, title) {
-			return title
+			return title, confidenceGenericLoose, fmt.Sprintf("subject known title %q", title)
 		}
 	}
 
-	return "Unknown Position"
+	return "Unknown Position", 0, ""
 }
 
 // extract title from body
-func extractTitleFromBody(body string) string {
+func extractTitleFromBody(body string) (string, float64, string) {
 	if body == "" {
-		return "Unknown Position"
+		return "Unknown Position", 0, ""
 	}
 
 	lines := strings.Split(body, "\n")
 
-	// LinkedIn-specific logic: look for job title after company line
-	foundCompanyLine := false
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		// Remove markdown formatting
-		cleanLine := strings.ReplaceAll(line, "**", "")
-		cleanLine = strings.ReplaceAll(cleanLine, "*", "")
-		cleanLine = strings.TrimSpace(cleanLine)
-
-		// Check if this line contains "Your application was sent to"
-		if strings.Contains(strings.ToLower(cleanLine), "your application was sent to") {
-			foundCompanyLine = true
-			continue
-		}
-
-		// If we found the company line, the next non-empty line should be the job title
-		if foundCompanyLine && cleanLine != "" {
-			// Clean up the job title line - remove company name and location info
-			title := cleanLine
-
-			// Remove company name and location patterns
-			// Look for patterns like "Company Name · Location" or "Company Name - Location"
-			parts := strings.Split(title, "·")
-			if len(parts) > 1 {
-				title = strings.TrimSpace(parts[0])
-			}
-
-			parts = strings.Split(title, " - ")
-			if len(parts) > 1 && !strings.Contains(strings.ToLower(parts[1]), "developer") {
-				title = strings.TrimSpace(parts[0])
-			}
-
-			// Remove "Applied on" and date information
-			if strings.Contains(strings.ToLower(title), "applied on") {
-				continue
-			}
-
-			// Validate the title
-			if len(title) > 1 && len(title) < 100 && !isLocationOrDate(title) {
-				return title
-			}
-		}
-	}
-
-	// Fallback to general body parsing
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 
 		// Look for job title patterns in body
-		patterns := []string{
-			`(?i)position:\s+([^,\n]+)`,
-			`(?i)role:\s+([^,\n]+)`,
-			`(?i)for\s+the\s+([^,\n]+?)\s+position`,
-			`(?i)([A-Za-z\s]+?)\s+position`,
+		patterns := []scoredPattern{
+			{`(?i)position:\s+([^,\n]+)`, confidenceGeneric},
+			{`(?i)role:\s+([^,\n]+)`, confidenceGeneric},
+			{`(?i)for\s+the\s+([^,\n]+?)\s+position`, confidenceGeneric},
+			{`(?i)([A-Za-z\s]+?)\s+position`, 0.4},
 		}
 
 		for _, pattern := range patterns {
-			re := regexp.MustCompile(pattern)
+			re := regexp.MustCompile(pattern.expr)
 			matches := re.FindStringSubmatch(line)
 			if len(matches) > 1 {
 				title := strings.TrimSpace(matches[1])
 				if len(title) > 1 && len(title) < 100 {
-					return title
+					return title, pattern.confidence, patternSource("body", pattern.expr)
 				}
 			}
 		}
 	}
 
-	return "Unknown Position"
+	return "Unknown Position", 0, ""
 }
 
-// get job status from subject
-func determineJobStatus(subject string) string {
+// status keywords of the subject, checked in this order. Statuses the server does not list are skipped
+var statusKeywords = []struct {
+	status   string
+	keywords []string
+}{
+	{"interview", []string{"interview"}},
+	{"offer", []string{"offer", "congratulations"}},
+	{"rejected", []string{"reject", "unfortunately"}},
+	{"applied", []string{"received", "thank you"}},
+}
+
+// get job status from subject keywords, "applied" with low confidence when none is found
+func statusFromSubject(subject string) (string, float64, string) {
 	subjectLower := strings.ToLower(subject)
 
 	// Check for specific status indicators
-	if strings.Contains(subjectLower, "interview") {
-		return "interview"
-	}
-	if strings.Contains(subjectLower, "offer") || strings.Contains(subjectLower, "congratulations") {
-		return "offer"
-	}
-	if strings.Contains(subjectLower, "reject") || strings.Contains(subjectLower, "unfortunately") {
-		return "rejected"
-	}
-	if strings.Contains(subjectLower, "received") || strings.Contains(subjectLower, "thank you") {
-		return "applied"
+	for _, s := range statusKeywords {
+		if !slices.Contains(JobStatuses, s.status) {
+			continue
+		}
+		for _, keyword := range s.keywords {
+			if strings.Contains(subjectLower, keyword) {
+				return s.status, 0.8, fmt.Sprintf("subject keyword %q", keyword)
+			}
+		}
 	}
 
 	// Default status
-	return "applied"
+	return "applied", 0.5, "default, no status keyword in the subject"
 }
 
-// Helper function to check if a string looks like location or date info
-func isLocationOrDate(text string) bool {
-	textLower := strings.ToLower(text)
-
-	// Common location indicators
-	locationWords := []string{"toronto", "vancouver", "montreal", "calgary", "ottawa", "remote", "hybrid", "on-site", "ontario", "canada", "usa", "bc", "ab", "qc"}
-	for _, word := range locationWords {
-		if strings.Contains(textLower, word) {
-			return true
-		}
-	}
-
-	// Date patterns
-	if strings.Contains(textLower, "applied on") || strings.Contains(textLower, "2024") || strings.Contains(textLower, "2025") {
-		return true
-	}
-
-	// Check if it contains only location-like patterns (City, Province format)
-	if strings.Contains(text, ",") && len(strings.Split(text, ",")) == 2 {
-		return true
-	}
-
-	return false
+// "subject /(?i)position:\s+(.+)/"
+func patternSource(part, expr string) string {
+	return part + " /" + expr + "/"
 }
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden .json files in testdata/emails")
+
+// every testdata/emails/<name>.eml is read like a fetched email and its job compared with <name>.json
+func TestParseJobFromEmailGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "emails", "*.eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no fixtures in testdata/emails")
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".eml")
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			// the received date only matters for emails without a usable Date header
+			email, err := readEmail(f, time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false) // keep Message-IDs readable
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(ParseJobFromEmail(email)); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.Bytes()
+
+			golden := strings.TrimSuffix(file, ".eml") + ".json"
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -run Golden -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("parsed job changed, rerun with -update if this is intended\ngot:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
@@ -1,25 +1,440 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
+	"github.com/emersion/go-imap/client"
 	"github.com/joho/godotenv"
 )
 
+const usage = `usage: email_watcher [--dry-run [--explain]] [command]
+
+commands:
+  (none)     fetch job emails since the last run once and exit
+  watch      keep running and import job emails as they arrive
+  authorize  sign in with OAuth2 in the browser and save the token (IMAP_AUTH=xoauth2/oauthbearer)
+  validate-rules [rules file] [sample.eml|folder ...]
+             check the rules file (default RULES_FILE) and its tests, and show what it gets from samples
+  import [flags] file|folder ...
+             parse job emails of .eml files, mbox archives or Maildirs, see import -h
+  outbox list|retry [id ...]|purge id ...|all
+             show the jobs waiting to be sent to the server (OUTBOX_FILE), send them now or drop them
+
+flags (with no command or watch):
+  --dry-run  fetch and parse as usual but only print each email and its job as a JSON line:
+             nothing is sent to the server, the emails and watcher_state.json are left as they are
+  --explain  with --dry-run, show the parser, rule or pattern that found each field`
+
 func main() {
+	dryRun := flag.Bool("dry-run", false, "print parsed jobs instead of saving them")
+	explain := flag.Bool("explain", false, "with --dry-run, show what found each field")
+	flag.Usage = func() { fmt.Fprintln(flag.CommandLine.Output(), usage) }
+	flag.Parse()
+
+	command, args := "", []string(nil)
+	if flag.NArg() > 0 {
+		command, args = flag.Arg(0), flag.Args()[1:]
+	}
+	if command != "" && command != "watch" && command != "authorize" && command != "validate-rules" && command != "import" && command != "outbox" {
+		log.Fatalf("Unknown command %q\n%s", command, usage)
+	}
+
+	if command == "validate-rules" {
+		godotenv.Load() // only for RULES_FILE, no mailbox settings needed
+		runValidateRules(args)
+		return
+	}
+	if command == "import" {
+		godotenv.Load() // no mailbox settings needed
+		runImport(args)
+		return
+	}
+	if command == "outbox" {
+		godotenv.Load() // no mailbox settings needed
+		runOutbox(args)
+		return
+	}
+
+	// flags may also come after the command, "watch --dry-run"
+	flag.CommandLine.Parse(args)
+	if flag.NArg() > 0 {
+		log.Fatalf("Unexpected arguments %q\n%s", flag.Args(), usage)
+	}
+	if *explain && !*dryRun {
+		log.Fatalf("--explain only works with --dry-run\n%s", usage)
+	}
+	if *dryRun && command == "authorize" {
+		log.Fatalf("--dry-run does not apply to authorize\n%s", usage)
+	}
+
 	// load environment variable
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
 
+	imapConfig, err := IMAPConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if command == "authorize" {
+		runAuthorize(imapConfig)
+		return
+	}
+
 	email := os.Getenv("EMAIL_ADDRESS")
 	password := os.Getenv("EMAIL_PASSWORD")
+
+	if email == "" {
+		log.Fatal("Please set EMAIL_ADDRESS in .env file")
+	}
+
+	username := os.Getenv("IMAP_USERNAME") // some servers login with a user name, not the address
+	if username == "" {
+		username = email
+	}
+	auth, err := IMAPAuthFromEnv(imapConfig, username, password)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	useReviewThresholdFromEnv() // a dry run shows which jobs would wait for review
+	if !*dryRun {
+		useServerFromEnv()
+	}
+
+	checkpoints := &CheckpointStore{Path: os.Getenv("STATE_FILE")}
+	if checkpoints.Path == "" {
+		checkpoints.Path = "watcher_state.json"
+	}
+	useRulesFromEnv()
+	useCompanyDomainsFromEnv()
+
+	postAction, err := ParsePostAction(os.Getenv("POST_ACTION"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	outbox := outboxFromEnv()
+	process := newEmailProcessor(checkpoints, imapConfig.Mailbox, postAction, outbox)
+	if *dryRun {
+		report := NewDryRun(os.Stdout, *explain)
+		reportSkippedEmail = report.Skipped
+		process = newDryRunProcessor(checkpoints, imapConfig.Mailbox, report)
+	}
+
+	connect := func() (*client.Client, error) {
+		log.Printf("Connecting to %s (%s)...", imapConfig.Addr(), imapConfig.Security)
+		return ConnectIMAP(imapConfig, auth)
+	}
+
+	if command == "watch" {
+		if *dryRun {
+			outbox = nil // nothing to deliver
+		}
+		runWatch(connect, process, outbox)
+	} else {
+		runOnce(connect, process)
+	}
+}
+
+// connect, import new job emails and exit
+func runOnce(connect func() (*client.Client, error), process func(*client.Client) error) {
+	client, err := connect()
+	if err != nil {
+		log.Fatal("Failed to connect:", err)
+	}
+	defer client.Logout() // logout at the end
+
+	if err := process(client); err != nil {
+		log.Fatal("Failed to fetch emails:", err)
+	}
+
+	log.Println("Email process completed")
+}
+
+// keep importing job emails as they arrive until SIGINT/SIGTERM, and retry the jobs of the outbox
+func runWatch(connect func() (*client.Client, error), process func(*client.Client) error, outbox *Outbox) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if outbox != nil {
+		go outbox.Run(ctx, deliverOutboxItem)
+	}
+
+	watcher := &Watcher{
+		Connect:      connect,
+		Process:      process,
+		PollInterval: envDuration("POLL_INTERVAL", time.Minute),
+		MinBackoff:   time.Second,
+		MaxBackoff:   5 * time.Minute,
+	}
+
+	log.Println("Watching for new job emails (Ctrl+C to stop)...")
+	watcher.Run(ctx)
+	log.Println("Watcher stopped")
+}
+
+// sign in once in the browser and save the OAuth token, it is refreshed automatically afterwards
+func runAuthorize(imapConfig IMAPConfig) {
+	oauthConfig, err := OAuthConfigFromEnv(imapConfig.Host)
+	if err != nil {
+		log.Fatal(err)
+	}
+	port := 0 // any free port, loopback redirects may use any port
+	if value := os.Getenv("OAUTH_REDIRECT_PORT"); value != "" {
+		if port, err = strconv.Atoi(value); err != nil {
+			log.Fatalf("Invalid OAUTH_REDIRECT_PORT %q", value)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	tok, err := Authorize(ctx, *oauthConfig, port, func(url string) {
+		fmt.Printf("Open this URL in your browser and allow access to your mailbox:\n\n%s\n\n", url)
+	})
+	if err != nil {
+		log.Fatal("Authorization failed: ", err)
+	}
+
+	tokenFile := tokenFileFromEnv()
+	if err := tokenFile.Save(tok); err != nil {
+		log.Fatal("Failed to save token: ", err)
+	}
+	log.Printf("Saved OAuth token to %s", tokenFile.Path)
+}
+
+// check a rules file without connecting to anything, exits with 1 if it is invalid or a test fails
+func runValidateRules(args []string) {
+	path := os.Getenv("RULES_FILE")
+	if len(args) > 0 {
+		switch strings.ToLower(filepath.Ext(args[0])) {
+		case ".yaml", ".yml", ".json":
+			path, args = args[0], args[1:]
+		}
+	}
+	if path == "" {
+		log.Fatalf("No rules file, pass one or set RULES_FILE\n%s", usage)
+	}
+
+	rules, err := LoadRules(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s: %d rules OK\n", path, len(rules.rules))
+	useCompanyDomainsFromEnv()
+
+	ok, err := ValidateRules(rules, args, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// parse job emails from files instead of the mailbox, print them as JSON lines or post them with -post
+func runImport(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	since := flags.String("since", "", "only emails sent on or after this date (YYYY-MM-DD)")
+	until := flags.String("until", "", "only emails sent on or before this date (YYYY-MM-DD)")
+	from := flags.String("from", "", "only emails from these senders, comma separated addresses or domains")
+	all := flags.Bool("all", false, "also emails the subject keyword filter would skip")
+	post := flags.Bool("post", false, "send the jobs to the server (SERVER_URL, API_TOKEN) through the outbox instead of printing them")
+	explain := flags.Bool("explain", false, "print the parser, rule or pattern that found each field")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: email_watcher import [flags] file.eml|archive.mbox|maildir|folder ...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	for _, date := range []string{*since, *until} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			log.Fatalf("Invalid date %q, use YYYY-MM-DD", date)
+		}
+	}
+
+	filter := ImportFilter{Since: *since, Until: *until, All: *all}
+	if *from != "" {
+		filter.Senders = strings.Split(*from, ",")
+	}
+	var outbox *Outbox
+	if *post {
+		useServerFromEnv()
+		useReviewThresholdFromEnv()
+		outbox = outboxFromEnv()
+	}
+	useRulesFromEnv()
+	useCompanyDomainsFromEnv()
+
+	out := json.NewEncoder(os.Stdout)
+	out.SetEscapeHTML(false)
+	stats, err := ImportEmails(flags.Args(), filter, func(source string, email EmailData, job Job) error {
+		if *post {
+			_, err := outbox.Add(job, newEmailContext(email))
+			return err
+		}
+		line := struct {
+			Source string `json:"source"`
+			Job
+			Explain *Sources `json:"explain,omitempty"`
+		}{Source: source, Job: job}
+		if *explain {
+			line.Explain = &job.Sources
+		}
+		return out.Encode(line)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Read %d emails, %d job emails, %d jobs", stats.Read, stats.Matched, stats.Jobs)
+	if *post {
+		flushOutbox(outbox)
+	}
+}
+
+// show, resend or drop the jobs waiting in the outbox
+func runOutbox(args []string) {
+	const outboxUsage = "usage: email_watcher outbox list|retry [id ...]|purge id ...|all"
+	if len(args) == 0 {
+		log.Fatal(outboxUsage)
+	}
+	command, args := args[0], args[1:]
+	all := len(args) == 1 && args[0] == "all"
+	var ids []uint64
+	if !all {
+		for _, arg := range args {
+			id, err := strconv.ParseUint(arg, 10, 64)
+			if err != nil {
+				log.Fatalf("Invalid job ID %q\n%s", arg, outboxUsage)
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	outbox := outboxFromEnv()
+	switch command {
+	case "list":
+		if len(args) > 0 {
+			log.Fatal(outboxUsage)
+		}
+		items, err := outbox.List()
+		if err != nil {
+			log.Fatal(err)
+		}
+		dead, err := outbox.DeadLetters()
+		if err != nil {
+			log.Fatal(err)
+		}
+		printOutbox(os.Stdout, append(items, dead...))
+	case "retry":
+		useServerFromEnv()
+		useReviewThresholdFromEnv()
+		count, err := outbox.Retry(ids...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if count == 0 {
+			log.Println("Nothing to retry")
+			return
+		}
+		flushOutbox(outbox)
+	case "purge":
+		if len(args) == 0 {
+			log.Fatalf("Pass the IDs of the jobs to drop, or all\n%s", outboxUsage)
+		}
+		var count int
+		var err error
+		if all {
+			count, err = outbox.PurgeAll()
+		} else {
+			count, err = outbox.Purge(ids...)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Dropped %d jobs", count)
+	default:
+		log.Fatalf("Unknown outbox command %q\n%s", command, outboxUsage)
+	}
+}
+
+// one line per queued or given up job, with why its last delivery failed
+func printOutbox(w io.Writer, items []OutboxItem) {
+	if len(items) == 0 {
+		fmt.Fprintln(w, "The outbox is empty")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tJOB\tADDED\tATTEMPTS\tNEXT ATTEMPT\tLAST ERROR")
+	for _, item := range items {
+		next := "now"
+		if item.Dead {
+			next = "gave up"
+		} else if time.Until(item.NextAttempt) > 0 {
+			next = item.NextAttempt.Format(time.DateTime)
+		}
+		fmt.Fprintf(tw, "%d\t%s at %s\t%s\t%d\t%s\t%s\n", item.ID, item.Job.Title, item.Job.Company,
+			item.CreatedAt.Format(time.DateTime), item.Attempts, next, item.LastError)
+	}
+	tw.Flush()
+}
+
+// send the jobs that are due and say how many are still waiting
+func flushOutbox(outbox *Outbox) {
+	sent, err := outbox.Flush(deliverOutboxItem)
+	if err != nil {
+		log.Fatal("Outbox: ", err)
+	}
+	items, err := outbox.List()
+	if err != nil {
+		log.Fatal("Outbox: ", err)
+	}
+	dead, err := outbox.DeadLetters()
+	if err != nil {
+		log.Fatal("Outbox: ", err)
+	}
+	if len(items) > 0 || len(dead) > 0 {
+		log.Printf("Sent %d jobs, %d still in %s and %d given up on (see outbox list)", sent, len(items), outbox.Path, len(dead))
+		return
+	}
+	log.Printf("Sent %d jobs", sent)
+}
+
+// OUTBOX_FILE keeps the jobs that could not be sent yet
+func outboxFromEnv() *Outbox {
+	path := os.Getenv("OUTBOX_FILE")
+	if path == "" {
+		path = "outbox.db"
+	}
+	return &Outbox{Path: path, MinBackoff: 30 * time.Second, MaxBackoff: time.Hour}
+}
+
+// SERVER_URL and API_TOKEN of the server jobs are sent to, and the job statuses it accepts
+func useServerFromEnv() {
 	serverURL := os.Getenv("SERVER_URL")
+	apiToken := os.Getenv("API_TOKEN")
 
-	if email == "" || password == "" {
-		log.Fatal("Please set Email and password in .env file")
+	if apiToken == "" {
+		log.Fatal("Please set API_TOKEN in .env file (create one with POST /tokens on the server)")
 	}
 
 	if serverURL == "" {
@@ -29,36 +444,135 @@ func main() {
 
 	// Set Server URL for the api client
 	SetServerURL(serverURL)
+	SetAPIToken(apiToken)
 
-	//Connect to email
-	log.Println("Connecting to GMail...")
-	client, err := ConnectToGmail(email, password)
+	// rules and parsers only produce statuses the server accepts, the built-in list does while it is down
+	statuses, err := fetchStatuses()
 	if err != nil {
-		log.Fatal("Failed to connect:", err)
+		log.Printf("Could not load the job statuses from the server, using %s: %v", strings.Join(JobStatuses, ", "), err)
+		return
 	}
-	defer client.Logout() // logout at the end
+	SetJobStatuses(statuses)
+}
 
-	// fetch unread emails
-	log.Println("Fetching unread emails...")
-	emails, err := FetchUnreadEmails(client)
+// REVIEW_THRESHOLD of the review queue
+func useReviewThresholdFromEnv() {
+	if value := os.Getenv("REVIEW_THRESHOLD"); value != "" {
+		threshold, err := strconv.ParseFloat(value, 64)
+		if err != nil || threshold < 0 || threshold > 1 {
+			log.Fatalf("Invalid REVIEW_THRESHOLD %q, use a number from 0 to 1", value)
+		}
+		SetReviewThreshold(threshold)
+	}
+}
+
+// RULES_FILE adds parsing rules
+func useRulesFromEnv() {
+	path := os.Getenv("RULES_FILE")
+	if path == "" {
+		return
+	}
+	rules, err := LoadRules(path)
 	if err != nil {
-		log.Fatal("Failed to fetch emails:", err)
+		log.Fatal("Invalid rules file: ", err)
 	}
+	UseRules(rules)
+}
 
-	if len(emails) == 0 {
-		log.Println("No unread job-related emails found")
+// COMPANY_DOMAINS_FILE maps sender domains to company names
+func useCompanyDomainsFromEnv() {
+	path := os.Getenv("COMPANY_DOMAINS_FILE")
+	if path == "" {
 		return
 	}
+	companies, err := LoadCompanyDomains(path)
+	if err != nil {
+		log.Fatal("Invalid company domains file: ", err)
+	}
+	UseCompanyDomains(companies)
+}
 
-	//post each email to db
-	for _, email := range emails {
-		//extract job data from email
-		job := ParseJobFromEmail(email)
+// returns a func that fetches emails of the mailbox after the saved checkpoint, queues each job in the outbox
+// and sends it to the server (or to the review queue when it was parsed with low confidence),
+// applies the post action to the imported emails and then moves the checkpoint forward.
+// Jobs the server does not take stay in the outbox, the checkpoint still moves past their emails
+func newEmailProcessor(checkpoints *CheckpointStore, mailbox string, postAction PostAction, outbox *Outbox) func(c *client.Client) error {
+	return func(c *client.Client) error {
+		cp, err := checkpoints.Load()
+		if err != nil {
+			return fmt.Errorf("loading %s: %w", checkpoints.Path, err)
+		}
+
+		log.Printf("Fetching emails after UID %d...", cp.LastUID)
+		emails, cp, err := FetchNewEmails(c, mailbox, cp)
+		if err != nil {
+			return err
+		}
+
+		if len(emails) == 0 {
+			log.Println("No new job-related emails found")
+		}
+
+		//post each email to db
+		var imported []uint32
+		for _, email := range emails {
+			//extract job data from email
+			job := ParseJobFromEmail(email)
 
-		if job != nil {
-			sendJobToAPI(*job)
+			if job != nil {
+				if _, err := outbox.Add(*job, newEmailContext(email)); err != nil {
+					return fmt.Errorf("queueing job: %w", err)
+				}
+				imported = append(imported, email.UID)
+			}
 		}
+		if _, err := outbox.Flush(deliverOutboxItem); err != nil {
+			log.Printf("Failed to send the outbox: %v", err)
+		}
+
+		if err := postAction.Apply(c, imported); err != nil {
+			log.Printf("Failed to apply post action %s: %v", postAction.Kind, err)
+		}
+
+		return checkpoints.Save(cp)
 	}
+}
 
-	log.Println("Email process completed")
+// returns a func like newEmailProcessor's that only reports each email and its job: nothing is posted,
+// no post action is applied and the checkpoint only moves forward in memory, so a real run still imports them
+func newDryRunProcessor(checkpoints *CheckpointStore, mailbox string, report *DryRun) func(c *client.Client) error {
+	var cp *Checkpoint
+	return func(c *client.Client) error {
+		if cp == nil {
+			saved, err := checkpoints.Load()
+			if err != nil {
+				return fmt.Errorf("loading %s: %w", checkpoints.Path, err)
+			}
+			cp = &saved
+		}
+
+		emails, next, err := FetchNewEmails(c, mailbox, *cp)
+		if err != nil {
+			return err
+		}
+		for _, email := range emails {
+			report.Email(email)
+		}
+		*cp = next
+		return nil
+	}
+}
+
+// read a duration like "90s" or "5m" from the environment
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s %q, using %s", name, value, fallback)
+		return fallback
+	}
+	return d
 }
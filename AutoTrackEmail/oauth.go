@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+)
+
+// OAuth2 endpoints and IMAP scopes of the providers that support XOAUTH2/OAUTHBEARER, by IMAP host
+var oauthPresets = map[string]oauth2.Config{
+	"imap.gmail.com": {
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  "https://accounts.google.com/o/oauth2/auth",
+			TokenURL: "https://oauth2.googleapis.com/token",
+		},
+		Scopes: []string{"https://mail.google.com/"},
+	},
+	"outlook.office365.com": {
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  "https://login.microsoftonline.com/common/oauth2/v2.0/authorize",
+			TokenURL: "https://login.microsoftonline.com/common/oauth2/v2.0/token",
+		},
+		Scopes: []string{"https://outlook.office.com/IMAP.AccessAsUser.All", "offline_access"},
+	},
+}
+
+// OAuthConfigFromEnv reads OAUTH_CLIENT_ID and OAUTH_CLIENT_SECRET.
+// OAUTH_AUTH_URL, OAUTH_TOKEN_URL and OAUTH_SCOPES default to the IMAP host's provider
+func OAuthConfigFromEnv(imapHost string) (*oauth2.Config, error) {
+	cfg := oauthPresets[imapHost]
+	cfg.ClientID = os.Getenv("OAUTH_CLIENT_ID")
+	cfg.ClientSecret = os.Getenv("OAUTH_CLIENT_SECRET")
+
+	if authURL := os.Getenv("OAUTH_AUTH_URL"); authURL != "" {
+		cfg.Endpoint.AuthURL = authURL
+	}
+	if tokenURL := os.Getenv("OAUTH_TOKEN_URL"); tokenURL != "" {
+		cfg.Endpoint.TokenURL = tokenURL
+	}
+	if scopes := os.Getenv("OAUTH_SCOPES"); scopes != "" {
+		cfg.Scopes = strings.FieldsFunc(scopes, func(r rune) bool { return r == ',' || r == ' ' })
+	}
+
+	if cfg.ClientID == "" {
+		return nil, errors.New("please set OAUTH_CLIENT_ID (and OAUTH_CLIENT_SECRET) in .env file")
+	}
+	if cfg.Endpoint.AuthURL == "" || cfg.Endpoint.TokenURL == "" {
+		return nil, fmt.Errorf("no OAuth endpoints known for %s, set OAUTH_AUTH_URL and OAUTH_TOKEN_URL", imapHost)
+	}
+	return &cfg, nil
+}
+
+// TokenFile keeps the OAuth token, with its refresh token, in a JSON file
+type TokenFile struct {
+	Path string
+}
+
+func (f *TokenFile) Load() (*oauth2.Token, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no OAuth token in %s, run `email_watcher authorize` first", f.Path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var tok oauth2.Token
+	if err := json.Unmarshal(data, &tok); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", f.Path, err)
+	}
+	return &tok, nil
+}
+
+// Save writes the token atomically, readable only by the owner
+func (f *TokenFile) Save(tok *oauth2.Token) error {
+	data, err := json.MarshalIndent(tok, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f.Path, data)
+}
+
+// NewTokenSource returns the saved access token until it expires, then refreshes it
+// through the token endpoint and saves the new one
+func NewTokenSource(ctx context.Context, cfg *oauth2.Config, file *TokenFile) (oauth2.TokenSource, error) {
+	tok, err := file.Load()
+	if err != nil {
+		return nil, err
+	}
+	if tok.RefreshToken == "" {
+		log.Printf("%s has no refresh token, run `email_watcher authorize` again once it expires", file.Path)
+	}
+
+	return &savingTokenSource{
+		src:  oauth2.ReuseTokenSource(tok, cfg.TokenSource(ctx, tok)),
+		file: file,
+		last: tok.AccessToken,
+	}, nil
+}
+
+type savingTokenSource struct {
+	mu   sync.Mutex
+	src  oauth2.TokenSource
+	file *TokenFile
+	last string // access token in the file
+}
+
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tok, err := s.src.Token()
+	if err != nil {
+		return nil, err
+	}
+	if tok.AccessToken != s.last {
+		// a failed save only means refreshing again next time
+		if err := s.file.Save(tok); err != nil {
+			log.Printf("Failed to save refreshed OAuth token to %s: %v", s.file.Path, err)
+		}
+		s.last = tok.AccessToken
+	}
+	return tok, nil
+}
+
+// Authorize runs the authorization code flow with a loopback redirect (RFC 8252) and PKCE:
+// it listens on 127.0.0.1:port (0 picks a free port), shows the consent page URL with openURL
+// and exchanges the code the browser is redirected back with for a token
+func Authorize(ctx context.Context, cfg oauth2.Config, port int, openURL func(url string)) (*oauth2.Token, error) {
+	l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", fmt.Sprint(port)))
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	cfg.RedirectURL = fmt.Sprintf("http://%s/", l.Addr())
+	state := rand.Text()
+	verifier := oauth2.GenerateVerifier()
+
+	type callback struct {
+		code string
+		err  error
+	}
+	callbacks := make(chan callback, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("state") != state {
+			http.Error(w, "Invalid state", http.StatusBadRequest) // not our redirect, e.g. /favicon.ico
+			return
+		}
+
+		result := callback{code: query.Get("code")}
+		if errCode := query.Get("error"); errCode != "" || result.code == "" {
+			result.err = fmt.Errorf("authorization failed: %s %s", errCode, query.Get("error_description"))
+			fmt.Fprintln(w, "Authorization failed, check the terminal.")
+		} else {
+			fmt.Fprintln(w, "Authorized! You can close this tab and go back to the terminal.")
+		}
+
+		select {
+		case callbacks <- result:
+		default: // already got one
+		}
+	})}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	// offline access and consent make Google return a refresh token every time
+	openURL(cfg.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.ApprovalForce, oauth2.S256ChallengeOption(verifier)))
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-callbacks:
+		if result.err != nil {
+			return nil, result.err
+		}
+		return cfg.Exchange(ctx, result.code, oauth2.VerifierOption(verifier))
+	}
+}
+
+func tokenFileFromEnv() *TokenFile {
+	file := &TokenFile{Path: os.Getenv("OAUTH_TOKEN_FILE")}
+	if file.Path == "" {
+		file.Path = "oauth_token.json"
+	}
+	return file
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap/server"
+	"github.com/emersion/go-sasl"
+	"golang.org/x/oauth2"
+)
+
+// fake OAuth provider: /auth redirects straight back with a code, /token hands out numbered access tokens
+type fakeOAuthServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []map[string]string // form of each token request
+}
+
+func startFakeOAuthServer(t *testing.T) *fakeOAuthServer {
+	t.Helper()
+
+	fake := &fakeOAuthServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("code_challenge") == "" || q.Get("access_type") != "offline" {
+			http.Error(w, "missing PKCE challenge or offline access", http.StatusBadRequest)
+			return
+		}
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code=the-code&state="+q.Get("state"), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		fake.mu.Lock()
+		form := map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+		fake.requests = append(fake.requests, form)
+		n := len(fake.requests)
+		fake.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"access-` + strconv.Itoa(n) + `","token_type":"Bearer","expires_in":3600,"refresh_token":"refresh-1"}`))
+	})
+	fake.Server = httptest.NewServer(mux)
+	t.Cleanup(fake.Close)
+	return fake
+}
+
+func (f *fakeOAuthServer) config() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		Endpoint:     oauth2.Endpoint{AuthURL: f.URL + "/auth", TokenURL: f.URL + "/token"},
+		Scopes:       []string{"mail"},
+	}
+}
+
+func (f *fakeOAuthServer) tokenRequests() []map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests
+}
+
+func TestAuthorizeSavesTokenFromLoopbackRedirect(t *testing.T) {
+	fake := startFakeOAuthServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// the "browser" follows the redirect back to the loopback server
+	tok, err := Authorize(ctx, *fake.config(), 0, func(url string) {
+		go func() {
+			resp, err := http.Get(url)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "access-1" || tok.RefreshToken != "refresh-1" {
+		t.Fatalf("got token %+v", tok)
+	}
+
+	requests := fake.tokenRequests()
+	if len(requests) != 1 {
+		t.Fatalf("got %d token requests, want 1", len(requests))
+	}
+	exchange := requests[0]
+	if exchange["grant_type"] != "authorization_code" || exchange["code"] != "the-code" || exchange["code_verifier"] == "" {
+		t.Errorf("token request = %v, want the code exchanged with a PKCE verifier", exchange)
+	}
+}
+
+func TestTokenSourceRefreshesExpiredToken(t *testing.T) {
+	fake := startFakeOAuthServer(t)
+	file := &TokenFile{Path: filepath.Join(t.TempDir(), "token.json")}
+	expired := &oauth2.Token{AccessToken: "old", RefreshToken: "refresh-1", Expiry: time.Now().Add(-time.Hour)}
+	if err := file.Save(expired); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := NewTokenSource(context.Background(), fake.config(), file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		tok, err := tokens.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.AccessToken != "access-1" {
+			t.Fatalf("got access token %q, want the refreshed one", tok.AccessToken)
+		}
+	}
+
+	requests := fake.tokenRequests()
+	if len(requests) != 1 || requests[0]["grant_type"] != "refresh_token" || requests[0]["refresh_token"] != "refresh-1" {
+		t.Fatalf("token requests = %v, want a single refresh", requests)
+	}
+
+	saved, err := file.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.AccessToken != "access-1" || saved.RefreshToken != "refresh-1" {
+		t.Errorf("saved token %+v, want the refreshed token", saved)
+	}
+}
+
+func TestTokenFileMissing(t *testing.T) {
+	file := &TokenFile{Path: filepath.Join(t.TempDir(), "token.json")}
+	if _, err := NewTokenSource(context.Background(), &oauth2.Config{}, file); err == nil {
+		t.Fatal("want an error telling to run authorize")
+	}
+}
+
+// XOAUTH2 has no server in go-sasl, the initial response is "user=...^Aauth=Bearer ...^A^A"
+type xoauth2Server struct {
+	authenticate func(username, token string) error
+}
+
+func (s *xoauth2Server) Next(response []byte) ([]byte, bool, error) {
+	if response == nil {
+		return []byte{}, false, nil // ask for the initial response
+	}
+	parts := bytes.Split(response, []byte{1})
+	if len(parts) != 4 || !bytes.HasPrefix(parts[0], []byte("user=")) || !bytes.HasPrefix(parts[1], []byte("auth=Bearer ")) {
+		return nil, true, errors.New("invalid XOAUTH2 response")
+	}
+	username := string(bytes.TrimPrefix(parts[0], []byte("user=")))
+	token := string(bytes.TrimPrefix(parts[1], []byte("auth=Bearer ")))
+	return nil, true, s.authenticate(username, token)
+}
+
+func TestConnectWithOAuth(t *testing.T) {
+	var be *updatingBackend
+	login := func(conn server.Conn, username, token string) error {
+		if username != "me@example.com" || token != "access-1" {
+			return errors.New("invalid token")
+		}
+		user, err := be.Login(nil, "username", "password")
+		if err != nil {
+			return err
+		}
+		conn.Context().User = user
+		return nil
+	}
+
+	addr, be := startTestIMAPServer(t, func(s *server.Server) {
+		s.EnableAuth("XOAUTH2", func(conn server.Conn) sasl.Server {
+			return &xoauth2Server{authenticate: func(username, token string) error {
+				return login(conn, username, token)
+			}}
+		})
+		s.EnableAuth("OAUTHBEARER", func(conn server.Conn) sasl.Server {
+			return sasl.NewOAuthBearerServer(func(opts sasl.OAuthBearerOptions) *sasl.OAuthBearerError {
+				if err := login(conn, opts.Username, opts.Token); err != nil {
+					return &sasl.OAuthBearerError{Status: "invalid_token"}
+				}
+				return nil
+			})
+		})
+	})
+	host, port, _ := net.SplitHostPort(addr)
+	p, _ := strconv.Atoi(port)
+	cfg := IMAPConfig{Host: host, Port: p, Security: SecurityNone, Mailbox: "INBOX"}
+
+	for _, mechanism := range []string{AuthXOAuth2, AuthOAuthBearer} {
+		t.Run(mechanism, func(t *testing.T) {
+			good := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "access-1"})
+			c, err := ConnectIMAP(cfg, OAuthAuth(mechanism, "me@example.com", good))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Logout()
+			if _, err := c.Select("INBOX", true); err != nil {
+				t.Errorf("not logged in: %v", err)
+			}
+
+			bad := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "revoked"})
+			if c, err := ConnectIMAP(cfg, OAuthAuth(mechanism, "me@example.com", bad)); err == nil {
+				c.Logout()
+				t.Error("login with a bad token should fail")
+			}
+		})
+	}
+}
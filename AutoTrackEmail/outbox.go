@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Outbox is a durable queue of the jobs waiting to be sent to the server, kept in a bbolt file.
+// A job is added before the checkpoint moves past its email and only removed once the server
+// has it (2xx, or 409 when it already had it), so nothing is lost while the server is down.
+// Failed deliveries are retried with exponential backoff and jitter for as long as it takes.
+// Only jobs the server refuses for good (see permanent) are moved to the dead letters instead,
+// they stay there until retried or purged.
+// The file is only opened for each operation, so `outbox list` works while the watcher runs
+type Outbox struct {
+	Path       string
+	MinBackoff time.Duration // wait before the first retry
+	MaxBackoff time.Duration // longest wait between retries
+
+	flushing sync.Mutex // one delivery at a time, the watcher and its retry loop share the outbox
+}
+
+// OutboxItem is a queued job and how its delivery went so far
+type OutboxItem struct {
+	ID          uint64       `json:"id"`
+	Job         Job          `json:"job"`
+	Confidence  Confidence   `json:"confidence"` // not part of Job's JSON, decides about the review queue
+	Email       emailContext `json:"email"`      // shown in the review queue
+	Attempts    int          `json:"attempts"`
+	NextAttempt time.Time    `json:"next_attempt"` // zero until a delivery failed
+	LastError   string       `json:"last_error,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Dead        bool         `json:"dead,omitempty"` // given up on, only sent again by Retry
+}
+
+var (
+	outboxBucket     = []byte("jobs")
+	deadLetterBucket = []byte("dead")
+)
+
+// how long to wait for a process that has the file open, e.g. the watcher while `outbox list` runs
+const outboxLockTimeout = 10 * time.Second
+
+// Add queues a job, it is delivered by the next Flush
+func (o *Outbox) Add(job Job, email emailContext) (OutboxItem, error) {
+	item := OutboxItem{Job: job, Confidence: job.Confidence, Email: email, CreatedAt: time.Now()}
+	err := o.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(outboxBucket)
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		item.ID = id
+		return putOutboxItem(b, item)
+	})
+	return item, err
+}
+
+// List returns the queued jobs, oldest first
+func (o *Outbox) List() ([]OutboxItem, error) {
+	return o.list(outboxBucket)
+}
+
+// DeadLetters returns the jobs given up on, oldest first
+func (o *Outbox) DeadLetters() ([]OutboxItem, error) {
+	return o.list(deadLetterBucket)
+}
+
+func (o *Outbox) list(bucket []byte) ([]OutboxItem, error) {
+	var items []OutboxItem
+	err := o.view(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return nil // nothing queued yet
+		}
+		return b.ForEach(func(_, value []byte) error {
+			var item OutboxItem
+			if err := json.Unmarshal(value, &item); err != nil {
+				return err
+			}
+			items = append(items, item)
+			return nil
+		})
+	})
+	return items, err
+}
+
+// Retry makes the jobs with these IDs (all jobs when there are none) due now, returns how many there were.
+// Dead letters are queued again with their attempts reset
+func (o *Outbox) Retry(ids ...uint64) (int, error) {
+	count := 0
+	err := o.update(func(tx *bolt.Tx) error {
+		jobs, dead := tx.Bucket(outboxBucket), tx.Bucket(deadLetterBucket)
+		err := forEachOutboxItem(jobs, ids, func(item OutboxItem) error {
+			count++
+			item.NextAttempt = time.Time{}
+			return putOutboxItem(jobs, item)
+		})
+		if err != nil {
+			return err
+		}
+		return forEachOutboxItem(dead, ids, func(item OutboxItem) error {
+			count++
+			item.Attempts, item.NextAttempt, item.Dead = 0, time.Time{}, false
+			if err := dead.Delete(outboxKey(item.ID)); err != nil {
+				return err
+			}
+			return putOutboxItem(jobs, item)
+		})
+	})
+	return count, err
+}
+
+// Purge drops the jobs with these IDs without sending them, returns how many there were
+func (o *Outbox) Purge(ids ...uint64) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return o.purge(ids)
+}
+
+// PurgeAll empties the outbox and its dead letters, returns how many jobs they held
+func (o *Outbox) PurgeAll() (int, error) {
+	return o.purge(nil)
+}
+
+func (o *Outbox) purge(ids []uint64) (int, error) {
+	count := 0
+	err := o.update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{outboxBucket, deadLetterBucket} {
+			b := tx.Bucket(name)
+			err := forEachOutboxItem(b, ids, func(item OutboxItem) error {
+				count++
+				return b.Delete(outboxKey(item.ID))
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Flush delivers the jobs that are due, oldest first, and removes those deliver succeeds with.
+// A failed job is retried later, when the server cannot be reached at all the remaining jobs
+// wait for it instead of each timing out, they are not tried so their attempts do not grow.
+// A job the server refuses for good is moved to the dead letters. Returns how many jobs were delivered
+func (o *Outbox) Flush(deliver func(item OutboxItem) error) (int, error) {
+	o.flushing.Lock()
+	defer o.flushing.Unlock()
+
+	items, err := o.List()
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	var unreachable error
+	var retryAt time.Time // of the job that found the server unreachable
+	now := time.Now()
+	for _, item := range items {
+		if item.NextAttempt.After(now) {
+			continue
+		}
+		if unreachable != nil {
+			if err := o.postpone(item, unreachable, retryAt); err != nil {
+				return sent, err
+			}
+			continue
+		}
+
+		err := deliver(item)
+		if err == nil {
+			if err := o.remove(item.ID); err != nil {
+				return sent, err
+			}
+			sent++
+			continue
+		}
+
+		if permanent(err) {
+			log.Printf("✗ Gave up on %s at %s: %v, see outbox list and outbox retry %d", item.Job.Title, item.Job.Company, err, item.ID)
+			if err := o.bury(item, err); err != nil {
+				return sent, err
+			}
+			continue
+		}
+		log.Printf("✗ Could not send %s at %s (attempt %d): %v", item.Job.Title, item.Job.Company, item.Attempts+1, err)
+		next, saveErr := o.failed(item, err)
+		if saveErr != nil {
+			return sent, saveErr
+		}
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			unreachable, retryAt = err, next
+		}
+	}
+	return sent, nil
+}
+
+// Run flushes the outbox whenever a job is due until ctx is cancelled, it only returns ctx's error.
+// Jobs added by other processes are picked up within a minute
+func (o *Outbox) Run(ctx context.Context, deliver func(item OutboxItem) error) error {
+	for {
+		if _, err := o.Flush(deliver); err != nil {
+			log.Printf("Outbox: %v", err)
+		}
+
+		wait := time.Minute
+		if items, err := o.List(); err == nil {
+			for _, item := range items {
+				wait = min(wait, max(time.Until(item.NextAttempt), 0))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// deliverOutboxItem sends a queued job like processJob would have right away
+func deliverOutboxItem(item OutboxItem) error {
+	job := item.Job
+	job.Confidence = item.Confidence
+	return processJob(job, item.Email)
+}
+
+// record a failed attempt, returns when to try again
+func (o *Outbox) failed(item OutboxItem, err error) (time.Time, error) {
+	item.Attempts++
+	next := time.Now().Add(o.backoff(item.Attempts))
+	return next, o.postpone(item, err, next)
+}
+
+// record why the job is not sent yet and when to try again, without counting an attempt
+func (o *Outbox) postpone(item OutboxItem, err error, next time.Time) error {
+	item.LastError = err.Error()
+	item.NextAttempt = next
+	return o.update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(outboxBucket)
+		if b.Get(outboxKey(item.ID)) == nil {
+			return nil // purged meanwhile
+		}
+		return putOutboxItem(b, item)
+	})
+}
+
+// record the last attempt and move the job to the dead letters
+func (o *Outbox) bury(item OutboxItem, err error) error {
+	item.Attempts++
+	item.LastError = err.Error()
+	item.NextAttempt = time.Time{}
+	item.Dead = true
+	return o.update(func(tx *bolt.Tx) error {
+		jobs := tx.Bucket(outboxBucket)
+		key := outboxKey(item.ID)
+		if jobs.Get(key) == nil {
+			return nil // purged meanwhile
+		}
+		if err := jobs.Delete(key); err != nil {
+			return err
+		}
+		return putOutboxItem(tx.Bucket(deadLetterBucket), item)
+	})
+}
+
+// MinBackoff doubled for each attempt up to MaxBackoff, then a random half of it is taken off
+// so jobs that failed together are not all retried at the same moment
+func (o *Outbox) backoff(attempts int) time.Duration {
+	d := o.MinBackoff
+	for i := 1; i < attempts && d < o.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, o.MaxBackoff)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func (o *Outbox) remove(id uint64) error {
+	return o.update(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).Delete(outboxKey(id))
+	})
+}
+
+// runs fn in a write transaction, both buckets exist
+func (o *Outbox) update(fn func(tx *bolt.Tx) error) error {
+	db, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{outboxBucket, deadLetterBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return fn(tx)
+	})
+}
+
+// runs fn in a read transaction, the buckets are missing until something was queued
+func (o *Outbox) view(fn func(tx *bolt.Tx) error) error {
+	db, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.View(fn)
+}
+
+func (o *Outbox) open() (*bolt.DB, error) {
+	return bolt.Open(o.Path, 0o600, &bolt.Options{Timeout: outboxLockTimeout})
+}
+
+// calls fn with the items that have these IDs, or with every item when there are none
+func forEachOutboxItem(b *bolt.Bucket, ids []uint64, fn func(item OutboxItem) error) error {
+	var items []OutboxItem
+	err := b.ForEach(func(key, value []byte) error {
+		if len(ids) > 0 && !slices.Contains(ids, binary.BigEndian.Uint64(key)) {
+			return nil
+		}
+		var item OutboxItem
+		if err := json.Unmarshal(value, &item); err != nil {
+			return err
+		}
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// bbolt does not allow changing the bucket while iterating over it
+	for _, item := range items {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func putOutboxItem(b *bolt.Bucket, item OutboxItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return b.Put(outboxKey(item.ID), data)
+}
+
+// big endian so the keys sort in the order the jobs were added
+func outboxKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestOutbox(t *testing.T) *Outbox {
+	t.Helper()
+	return &Outbox{Path: filepath.Join(t.TempDir(), "outbox.db"), MinBackoff: time.Minute, MaxBackoff: time.Hour}
+}
+
+var confidentJob = Job{Company: "Acme", Title: "Backend Developer", Status: "applied", Confidence: Confidence{Company: 0.9, Title: 0.9, Status: 0.8}}
+
+func TestOutboxKeepsJobsUntilTheServerHasThem(t *testing.T) {
+	api := startFakeJobAPI(t)
+	api.jobStatus = http.StatusServiceUnavailable
+	outbox := newTestOutbox(t)
+
+	for range 2 {
+		if _, err := outbox.Add(confidentJob, emailContext{Subject: "Thank you for applying"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sent, err := outbox.Flush(deliverOutboxItem)
+	if err != nil || sent != 0 {
+		t.Fatalf("Flush() = %d, %v", sent, err)
+	}
+
+	items, err := outbox.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("%d jobs left, want 2", len(items))
+	}
+	for _, item := range items {
+		wait := time.Until(item.NextAttempt)
+		if item.Attempts != 1 || !strings.Contains(item.LastError, "503") || wait < 29*time.Second || wait > time.Minute {
+			t.Errorf("after a 503: %+v", item)
+		}
+	}
+
+	// not due yet
+	if sent, _ := outbox.Flush(deliverOutboxItem); sent != 0 || api.requestLog() != "POST /jobs, POST /jobs" {
+		t.Errorf("Flush() before the backoff sent %d, requests = %s", sent, api.requestLog())
+	}
+
+	api.jobStatus = http.StatusConflict // already saved counts as delivered
+	if count, err := outbox.Retry(items[0].ID); err != nil || count != 1 {
+		t.Fatalf("Retry() = %d, %v", count, err)
+	}
+	if sent, err := outbox.Flush(deliverOutboxItem); err != nil || sent != 1 {
+		t.Fatalf("Flush() after Retry = %d, %v", sent, err)
+	}
+	if left, _ := outbox.List(); len(left) != 1 || left[0].ID != items[1].ID {
+		t.Errorf("left %+v", left)
+	}
+}
+
+func TestOutboxSurvivesRestart(t *testing.T) {
+	api := startFakeJobAPI(t)
+	outbox := newTestOutbox(t)
+
+	unsure := Job{Company: "Unknown Company", Title: "Analyst", Status: "applied", Confidence: Confidence{Title: 0.3, Status: 0.5}}
+	if _, err := outbox.Add(unsure, emailContext{Subject: "Your application", From: "jobs@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// confidence is not part of a job's JSON but still decides about the review queue after a restart
+	reopened := &Outbox{Path: outbox.Path}
+	if sent, err := reopened.Flush(deliverOutboxItem); err != nil || sent != 1 {
+		t.Fatalf("Flush() = %d, %v", sent, err)
+	}
+	if got, want := api.requestLog(), "POST /reviews"; got != want {
+		t.Errorf("requests = %s, want %s", got, want)
+	}
+	if email := api.reviews[0].Email; email.Subject != "Your application" || email.From != "jobs@example.com" {
+		t.Errorf("email = %+v", email)
+	}
+}
+
+func TestOutboxWaitsForUnreachableServer(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	previous := ServerURL
+	ServerURL = server.URL
+	t.Cleanup(func() { ServerURL = previous })
+
+	outbox := newTestOutbox(t)
+	for range 3 {
+		if _, err := outbox.Add(confidentJob, emailContext{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	calls := 0
+	sent, err := outbox.Flush(func(item OutboxItem) error {
+		calls++
+		return deliverOutboxItem(item)
+	})
+	if err != nil || sent != 0 {
+		t.Fatalf("Flush() = %d, %v", sent, err)
+	}
+	if calls != 1 {
+		t.Errorf("tried %d jobs, want only the first one", calls)
+	}
+
+	// the jobs that were not tried wait for the first one without losing an attempt
+	outbox.Retry()
+	outbox.Flush(deliverOutboxItem)
+	items, _ := outbox.List()
+	if len(items) != 3 {
+		t.Fatalf("%d jobs left, want 3", len(items))
+	}
+	for i, item := range items {
+		want := 0
+		if i == 0 {
+			want = 2
+		}
+		if item.Attempts != want || item.LastError == "" || !item.NextAttempt.Equal(items[0].NextAttempt) {
+			t.Errorf("job %d not postponed with the first one: %+v", item.ID, item)
+		}
+	}
+}
+
+func TestOutboxPurge(t *testing.T) {
+	outbox := newTestOutbox(t)
+	for range 3 {
+		if _, err := outbox.Add(confidentJob, emailContext{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count, err := outbox.Purge(2, 9); err != nil || count != 1 {
+		t.Fatalf("Purge(2, 9) = %d, %v", count, err)
+	}
+	items, _ := outbox.List()
+	if len(items) != 2 || items[0].ID != 1 || items[1].ID != 3 {
+		t.Errorf("left %+v", items)
+	}
+	if count, err := outbox.Purge(); err != nil || count != 0 {
+		t.Errorf("Purge() = %d, %v, want nothing dropped", count, err)
+	}
+	if count, err := outbox.PurgeAll(); err != nil || count != 2 {
+		t.Errorf("PurgeAll() = %d, %v", count, err)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	outbox := &Outbox{MinBackoff: time.Second, MaxBackoff: 10 * time.Second}
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 8 * time.Second, 5: 10 * time.Second, 30: 10 * time.Second} {
+		for range 20 {
+			if got := outbox.backoff(attempts); got < want/2 || got > want {
+				t.Errorf("backoff(%d) = %s, want between %s and %s", attempts, got, want/2, want)
+			}
+		}
+	}
+}
+
+func TestOutboxGivesUpOnRefusedJobs(t *testing.T) {
+	for _, tt := range []struct {
+		status int
+		dead   bool
+	}{
+		{http.StatusBadRequest, true},
+		{http.StatusUnauthorized, true},
+		{http.StatusForbidden, true},
+		{http.StatusUnprocessableEntity, true},
+		{http.StatusRequestTimeout, false},
+		{http.StatusTooManyRequests, false},
+		{http.StatusInternalServerError, false},
+	} {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			api := startFakeJobAPI(t)
+			api.jobStatus = tt.status
+			outbox := newTestOutbox(t)
+			if _, err := outbox.Add(confidentJob, emailContext{}); err != nil {
+				t.Fatal(err)
+			}
+			if sent, err := outbox.Flush(deliverOutboxItem); err != nil || sent != 0 {
+				t.Fatalf("Flush() = %d, %v", sent, err)
+			}
+
+			items, _ := outbox.List()
+			dead, _ := outbox.DeadLetters()
+			if tt.dead && (len(items) != 0 || len(dead) != 1 || !dead[0].Dead || dead[0].Attempts != 1 || dead[0].LastError == "") {
+				t.Errorf("queued %+v, dead letters %+v, want the job given up on", items, dead)
+			}
+			if !tt.dead && (len(items) != 1 || len(dead) != 0) {
+				t.Errorf("queued %+v, dead letters %+v, want the job retried later", items, dead)
+			}
+		})
+	}
+}
+
+func TestOutboxGivesUpOnFollowUpsItCannotMatch(t *testing.T) {
+	api := startFakeJobAPI(t)
+	api.matchError = http.StatusForbidden
+	outbox := newTestOutbox(t)
+	if _, err := outbox.Add(rejection, emailContext{}); err != nil {
+		t.Fatal(err)
+	}
+
+	outbox.Flush(deliverOutboxItem)
+	outbox.Flush(deliverOutboxItem)
+	if got, want := api.requestLog(), "GET /jobs/match"; got != want {
+		t.Errorf("requests = %s, want %s", got, want)
+	}
+	if dead, _ := outbox.DeadLetters(); len(dead) != 1 || !strings.Contains(dead[0].LastError, "jobs:read") {
+		t.Errorf("dead letters %+v", dead)
+	}
+}
+
+func TestOutboxKeepsRetryingTransientFailures(t *testing.T) {
+	api := startFakeJobAPI(t)
+	api.jobStatus = http.StatusServiceUnavailable
+	outbox := newTestOutbox(t)
+	if _, err := outbox.Add(confidentJob, emailContext{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a server that is down for days never loses a job
+	for range 50 {
+		outbox.Retry()
+		outbox.Flush(deliverOutboxItem)
+	}
+	items, _ := outbox.List()
+	dead, _ := outbox.DeadLetters()
+	if len(items) != 1 || len(dead) != 0 || items[0].Attempts != 50 {
+		t.Fatalf("queued %+v, dead letters %+v, want the job still queued after 50 attempts", items, dead)
+	}
+	if wait := time.Until(items[0].NextAttempt); wait < outbox.MaxBackoff/2-time.Second || wait > outbox.MaxBackoff {
+		t.Errorf("next attempt in %s, want at most MaxBackoff %s", wait, outbox.MaxBackoff)
+	}
+}
+
+func TestOutboxRetriesDeadLetters(t *testing.T) {
+	api := startFakeJobAPI(t)
+	api.jobStatus = http.StatusUnauthorized
+	outbox := newTestOutbox(t)
+	item, err := outbox.Add(confidentJob, emailContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	outbox.Flush(deliverOutboxItem)
+	if dead, _ := outbox.DeadLetters(); len(dead) != 1 {
+		t.Fatalf("dead letters %+v", dead)
+	}
+
+	// e.g. after replacing API_TOKEN, the job is queued again from scratch
+	api.jobStatus = 0
+	if count, err := outbox.Retry(item.ID); err != nil || count != 1 {
+		t.Fatalf("Retry(%d) = %d, %v", item.ID, count, err)
+	}
+	if items, _ := outbox.List(); len(items) != 1 || items[0].Dead || items[0].Attempts != 0 {
+		t.Errorf("queued %+v", items)
+	}
+	if sent, err := outbox.Flush(deliverOutboxItem); err != nil || sent != 1 {
+		t.Errorf("Flush() = %d, %v", sent, err)
+	}
+	if dead, _ := outbox.DeadLetters(); len(dead) != 0 {
+		t.Errorf("dead letters %+v", dead)
+	}
+}
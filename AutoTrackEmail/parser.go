@@ -0,0 +1,272 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Parser extracts a job from the emails of one sender, usually an applicant tracking system (ATS)
+type Parser interface {
+	Name() string
+	Match(email EmailData) bool // whether this parser knows the email's format
+	Parse(email EmailData) *Job // nil if nothing could be extracted
+}
+
+// ParserRegistry picks the parser for an email: the first registered parser that matches,
+// or the fallback. Fields a parser leaves empty are filled by the fallback.
+// A parser that also implements ignorer can drop an email entirely.
+// With a CompanyResolver the sender decides the company unless a dedicated parser found one
+type ParserRegistry struct {
+	parsers   []Parser
+	fallback  Parser
+	companies *CompanyResolver
+}
+
+func NewParserRegistry(fallback Parser) *ParserRegistry {
+	return &ParserRegistry{fallback: fallback}
+}
+
+// Register adds parsers, they are tried in the order they were registered
+func (r *ParserRegistry) Register(parsers ...Parser) {
+	r.parsers = append(r.parsers, parsers...)
+}
+
+func (r *ParserRegistry) ParserFor(email EmailData) Parser {
+	for _, p := range r.parsers {
+		if p.Match(email) {
+			return p
+		}
+	}
+	return r.fallback
+}
+
+// ignorer is implemented by parsers that can tell an email is not an application, e.g. job alerts
+type ignorer interface {
+	Ignore(email EmailData) bool
+}
+
+// ResolveCompanies adds the company-resolution stage, see CompanyResolver
+func (r *ParserRegistry) ResolveCompanies(companies *CompanyResolver) {
+	r.companies = companies
+}
+
+func (r *ParserRegistry) Parse(email EmailData) *Job {
+	p := r.ParserFor(email)
+	if ig, ok := p.(ignorer); ok && ig.Ignore(email) {
+		return nil
+	}
+	job := p.Parse(email)
+	parsedCompany := p != r.fallback && job != nil && job.Company != ""
+
+	if p != r.fallback {
+		job = r.fillFromFallback(job, email)
+	}
+	if job != nil && r.companies != nil {
+		r.resolveCompany(job, email, parsedCompany)
+	}
+	return job
+}
+
+func (r *ParserRegistry) fillFromFallback(job *Job, email EmailData) *Job {
+	fallback := r.fallback.Parse(email)
+	if job == nil {
+		return fallback
+	}
+	if fallback != nil {
+		if job.Company == "" {
+			job.Company, job.Confidence.Company, job.Sources.Company = fallback.Company, fallback.Confidence.Company, fallback.Sources.Company
+		}
+		if job.Title == "" {
+			job.Title, job.Confidence.Title, job.Sources.Title = fallback.Title, fallback.Confidence.Title, fallback.Sources.Title
+		}
+	}
+	return job
+}
+
+// the mapping file always wins, a company from a dedicated parser beats the sender, and the
+// sender beats the generic heuristics unless they agree ("Wayne Enterprises" from the body
+// is kept over "Wayne" from jane@wayne.com, and is more likely right)
+func (r *ParserRegistry) resolveCompany(job *Job, email EmailData, parsedCompany bool) {
+	company, confidence, source := r.companies.Resolve(email)
+	switch {
+	case company == "":
+	case confidence == confidenceRule:
+		job.Company, job.Confidence.Company, job.Sources.Company = company, confidence, parserSource("sender", source)
+	case parsedCompany:
+	case job.Company == "" || job.Company == "Unknown Company" || !sameCompany(job.Company, company):
+		job.Company, job.Confidence.Company, job.Sources.Company = company, confidence, parserSource("sender", source)
+	default:
+		job.Confidence.Company = min(max(job.Confidence.Company, confidence)+0.2, confidenceATS)
+		job.Sources.Company += ", the sender agrees (" + source + ")"
+	}
+}
+
+// parsers used by ParseJobFromEmail, see UseRules and UseCompanyDomains
+var defaultParsers = newDefaultParserRegistry(nil, NewCompanyResolver(nil))
+
+// rules from the rules file, if any, come before the built-in parsers
+func newDefaultParserRegistry(rules *Rules, companies *CompanyResolver) *ParserRegistry {
+	r := NewParserRegistry(genericParser{})
+	if rules != nil {
+		r.Register(rulesParser{rules})
+	}
+	r.Register(atsParsers...)
+	r.ResolveCompanies(companies)
+	return r
+}
+
+// Extract job info from email data with the parser that fits the sender
+func ParseJobFromEmail(email EmailData) *Job {
+	return defaultParsers.Parse(email)
+}
+
+// atsParser recognises an ATS by its sender domains, by its domains in the routing headers
+// (relayed emails come from the employer's address) or by text only found in its emails,
+// and extracts with patterns that use the named groups "company" and "title"
+type atsParser struct {
+	name      string
+	domains   []string // subdomains match too
+	bodyHints []string // lower case
+	subject   []*regexp.Regexp
+	body      []*regexp.Regexp
+}
+
+// headers that still point at the ATS when it sends on behalf of the employer
+var fingerprintHeaders = []string{"Message-Id", "Return-Path", "Sender", "Reply-To", "List-Unsubscribe", "X-Mailer"}
+
+func (p *atsParser) Name() string {
+	return p.name
+}
+
+func (p *atsParser) Match(email EmailData) bool {
+	if p.fromDomain(email.From) {
+		return true
+	}
+
+	for _, name := range fingerprintHeaders {
+		for _, host := range headerHosts(email.Header.Get(name)) {
+			if p.hasDomain(host) {
+				return true
+			}
+		}
+	}
+
+	body := strings.ToLower(email.Body)
+	for _, hint := range p.bodyHints {
+		if strings.Contains(body, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *atsParser) fromDomain(from string) bool {
+	_, domain, ok := strings.Cut(strings.ToLower(from), "@")
+	return ok && p.hasDomain(domain)
+}
+
+// hasDomain reports whether host is one of the parser's domains or a
+// subdomain of one, so unilever.com does not count as lever.co.
+func (p *atsParser) hasDomain(host string) bool {
+	for _, d := range p.domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerHosts splits a header value such as "<bounces+1@us.greenhouse-mail.io>"
+// or "<https://jobs.lever.co/unsubscribe>" into the host names it mentions.
+func headerHosts(value string) []string {
+	words := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '-')
+	})
+	var hosts []string
+	for _, word := range words {
+		if strings.Contains(word, ".") {
+			hosts = append(hosts, strings.Trim(word, "."))
+		}
+	}
+	return hosts
+}
+
+func (p *atsParser) Parse(email EmailData) *Job {
+	company, companySource := namedMatchSource(p.subject, p.body, email, "company")
+	title, titleSource := namedMatchSource(p.subject, p.body, email, "title")
+
+	if company == "" && title == "" {
+		return nil
+	}
+	status, statusConfidence, statusSource := statusFromSubject(email.Subject)
+	return &Job{
+		Company:         company,
+		Title:           title,
+		Status:          status,
+		AppliedDate:     email.Date,
+		Notes:           email.Subject,
+		SourceMessageID: email.MessageID,
+		Confidence: Confidence{
+			Company: foundConfidence(company, confidenceATS),
+			Title:   foundConfidence(title, confidenceATS),
+			Status:  statusConfidence,
+		},
+		Sources: Sources{
+			Company: parserSource(p.name, companySource),
+			Title:   parserSource(p.name, titleSource),
+			Status:  parserSource(p.name, statusSource),
+		},
+	}
+}
+
+// "lever", "subject /.../" -> "lever subject /.../", "" when nothing was found
+func parserSource(parser, source string) string {
+	if source == "" {
+		return ""
+	}
+	return parser + " " + source
+}
+
+// confidence of a method for a field it filled, 0 if it found nothing
+func foundConfidence(value string, confidence float64) float64 {
+	if value == "" {
+		return 0
+	}
+	return confidence
+}
+
+// value of the named group in the subject patterns, then in the body patterns, and which pattern found it
+func namedMatchSource(subject, body []*regexp.Regexp, email EmailData, group string) (string, string) {
+	if value, re := firstNamedMatch(subject, email.Subject, group); re != nil {
+		return value, patternSource("subject", re.String())
+	}
+	if value, re := firstNamedMatch(body, email.Body, group); re != nil {
+		return value, patternSource("body", re.String())
+	}
+	return "", ""
+}
+
+// value of the named group in the first pattern that matches text and has it, and that pattern
+func firstNamedMatch(patterns []*regexp.Regexp, text, group string) (string, *regexp.Regexp) {
+	for _, re := range patterns {
+		i := re.SubexpIndex(group)
+		if i < 0 {
+			continue
+		}
+		matches := re.FindStringSubmatch(text)
+		if matches == nil {
+			continue
+		}
+		if value := cleanExtracted(matches[i]); len(value) > 1 && len(value) < 100 {
+			return value, re
+		}
+	}
+	return "", nil
+}
+
+// strip markdown emphasis, surrounding punctuation and extra spaces left by the patterns
+func cleanExtracted(value string) string {
+	value = strings.ReplaceAll(value, "*", "")
+	value = strings.Join(strings.Fields(value), " ")
+	return strings.Trim(value, " .,!:;-\"'")
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/emersion/go-message"
+)
+
+func TestParserRegistryPicksParser(t *testing.T) {
+	tests := []struct {
+		name       string
+		email      EmailData
+		wantParser string
+		wantJob    Job
+	}{
+		{
+			name: "linkedin",
+			email: EmailData{
+				From:    "jobs-noreply@linkedin.com",
+				Subject: "Your application was sent to Acme Corp",
+				Body:    "Your application was sent to Acme Corp\n\n**Backend Developer**\nAcme Corp · Toronto, ON (Hybrid)\nApplied on October 7, 2025",
+			},
+			wantParser: "linkedin",
+			wantJob:    Job{Company: "Acme Corp", Title: "Backend Developer", Status: "applied"},
+		},
+		{
+			name: "greenhouse relayed from the employer",
+			email: EmailData{
+				From:    "no-reply@globex.com",
+				Subject: "Thank you for applying to Globex",
+				Body:    "Hi Sam,\n\nThank you for your interest in Globex! We wanted to let you know we received your application for Site Reliability Engineer, and we are delighted that you would consider joining our team.",
+				Header:  testHeader("Return-Path", "<bounces+123@us.greenhouse-mail.io>"),
+			},
+			wantParser: "greenhouse",
+			wantJob:    Job{Company: "Globex", Title: "Site Reliability Engineer", Status: "applied"},
+		},
+		{
+			name: "lever",
+			email: EmailData{
+				From:    "no-reply@hire.lever.co",
+				Subject: "Thank you for your application to Initech",
+				Body:    "Hi Sam,\n\nThanks for applying to Initech. We will review your application for the Data Engineer position and get back to you.",
+			},
+			wantParser: "lever",
+			wantJob:    Job{Company: "Initech", Title: "Data Engineer", Status: "applied"},
+		},
+		{
+			name: "workday",
+			email: EmailData{
+				From:    "umbrella@myworkday.com",
+				Subject: "Application Received: Frontend Developer (R-10422)",
+				Body:    "Dear Sam,\n\nThank you for applying to the Frontend Developer position R-10422 at Umbrella Corporation. Our team will review your application.",
+			},
+			wantParser: "workday",
+			wantJob:    Job{Company: "Umbrella Corporation", Title: "Frontend Developer", Status: "applied"},
+		},
+		{
+			name: "lever unsubscribe link",
+			email: EmailData{
+				From:    "no-reply@initech.com",
+				Subject: "Thank you for your application to Initech",
+				Body:    "Hi Sam,\n\nThanks for applying to Initech. We will review your application for the Data Engineer position and get back to you.",
+				Header:  testHeader("List-Unsubscribe", "<https://jobs.lever.co/initech/unsubscribe>"),
+			},
+			wantParser: "lever",
+			wantJob:    Job{Company: "Initech", Title: "Data Engineer", Status: "applied"},
+		},
+		{
+			name: "unilever is not lever",
+			email: EmailData{
+				From:    "careers@unilever.com",
+				Subject: "Thank you for applying to Unilever",
+				Body:    "We have received your application for the Brand Manager position.",
+				Header:  testHeader("Return-Path", "<careers@unilever.com>", "Message-Id", "<123.abc@mail.unilever.com>"),
+			},
+			wantParser: "generic",
+			wantJob:    Job{Company: "Unilever", Title: "Brand Manager", Status: "applied"},
+		},
+		{
+			name: "icims",
+			email: EmailData{
+				From:    "careers@hooli.icims.com",
+				Subject: "Thank you for applying for the Product Manager position",
+				Body:    "Thank you for your interest in employment with Hooli. We have received your application for the position of Product Manager.",
+			},
+			wantParser: "icims",
+			wantJob:    Job{Company: "Hooli", Title: "Product Manager", Status: "applied"},
+		},
+		{
+			name: "smartrecruiters",
+			email: EmailData{
+				From:    "noreply@smartrecruiters.com",
+				Subject: "Your application for QA Analyst at Vandelay Industries",
+				Body:    "Thank you for applying for the QA Analyst position at Vandelay Industries.",
+			},
+			wantParser: "smartrecruiters",
+			wantJob:    Job{Company: "Vandelay Industries", Title: "QA Analyst", Status: "applied"},
+		},
+		{
+			name: "ashby",
+			email: EmailData{
+				From:    "no-reply@ashbyhq.com",
+				Subject: "Thanks for applying to Pied Piper!",
+				Body:    "Hi Sam, thanks for applying for the Platform Engineer role at Pied Piper. We'll be in touch.",
+			},
+			wantParser: "ashby",
+			wantJob:    Job{Company: "Pied Piper", Title: "Platform Engineer", Status: "applied"},
+		},
+		{
+			name: "indeed",
+			email: EmailData{
+				From:    "indeedapply@indeed.com",
+				Subject: "Indeed Application: Mobile Developer",
+				Body:    "Application submitted\nMobile Developer\nStark Industries - Vancouver, BC\n\nThe following items were sent to Stark Industries.",
+			},
+			wantParser: "indeed",
+			wantJob:    Job{Company: "Stark Industries", Title: "Mobile Developer", Status: "applied"},
+		},
+		{
+			name: "unknown sender uses the generic parser",
+			email: EmailData{
+				From:    "talent@wayne.example",
+				Subject: "Thank you for applying to Wayne Enterprises",
+				Body:    "We have received your application for the Security Analyst position.",
+			},
+			wantParser: "generic",
+			wantJob:    Job{Company: "Wayne Enterprises", Title: "Security Analyst", Status: "applied"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := defaultParsers.ParserFor(tt.email).Name(); got != tt.wantParser {
+				t.Errorf("parser = %s, want %s", got, tt.wantParser)
+			}
+
+			job := ParseJobFromEmail(tt.email)
+			if job == nil {
+				t.Fatal("no job parsed")
+			}
+			if job.Company != tt.wantJob.Company || job.Title != tt.wantJob.Title || job.Status != tt.wantJob.Status {
+				t.Errorf("got company %q, title %q, status %q, want %q, %q, %q",
+					job.Company, job.Title, job.Status, tt.wantJob.Company, tt.wantJob.Title, tt.wantJob.Status)
+			}
+		})
+	}
+}
+
+func TestParserRegistryFillsMissingFields(t *testing.T) {
+	// the LinkedIn subject has the company, the title only matches a generic body pattern
+	email := EmailData{
+		From:    "jobs-noreply@linkedin.com",
+		Subject: "Your application was viewed by Acme Corp",
+		Body:    "Position: Backend Developer",
+	}
+
+	job := ParseJobFromEmail(email)
+	if job == nil || job.Company != "Acme Corp" || job.Title != "Backend Developer" {
+		t.Fatalf("got %+v, want the company from LinkedIn and the title from the generic parser", job)
+	}
+}
+
+func testHeader(kv ...string) message.Header {
+	var h message.Header
+	for i := 0; i < len(kv); i += 2 {
+		h.Set(kv[i], kv[i+1])
+	}
+	return h
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// what to do with job emails once they have been imported
+const (
+	PostActionNone     = "none"  // leave the email as it was (unread stays unread)
+	PostActionMarkRead = "read"  // add \Seen
+	PostActionLabel    = "label" // add a Gmail label
+	PostActionMove     = "move"  // move to another folder
+)
+
+type PostAction struct {
+	Kind   string
+	Target string // label or folder name
+}
+
+// ParsePostAction parses POST_ACTION: "none" (or "unread"), "read", "label:<name>" or "move:<folder>"
+func ParsePostAction(s string) (PostAction, error) {
+	kind, target, _ := strings.Cut(strings.TrimSpace(s), ":")
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	target = strings.TrimSpace(target)
+
+	switch kind {
+	case "", PostActionNone, "unread":
+		return PostAction{Kind: PostActionNone}, nil
+	case PostActionMarkRead:
+		return PostAction{Kind: PostActionMarkRead}, nil
+	case PostActionLabel, PostActionMove:
+		if target == "" {
+			return PostAction{}, fmt.Errorf("post action %q needs a name, e.g. %s:Jobs", kind, kind)
+		}
+		return PostAction{Kind: kind, Target: target}, nil
+	}
+	return PostAction{}, fmt.Errorf("unknown post action %q, use none, read, label:<name> or move:<folder>", s)
+}
+
+// Apply runs the action on messages of the selected mailbox
+func (a PostAction) Apply(c *client.Client, uids []uint32) error {
+	if a.Kind == PostActionNone || len(uids) == 0 {
+		return nil
+	}
+
+	seqSet := new(imap.SeqSet)
+	seqSet.AddNum(uids...)
+
+	switch a.Kind {
+	case PostActionMarkRead:
+		return c.UidStore(seqSet, imap.FormatFlagsOp(imap.AddFlags, true), []interface{}{imap.SeenFlag}, nil)
+	case PostActionLabel:
+		// Gmail extension, labels are quoted as they may contain spaces
+		return c.UidStore(seqSet, "+X-GM-LABELS.SILENT", []interface{}{imap.RawString(quoteIMAPString(a.Target))}, nil)
+	case PostActionMove:
+		// the client falls back to COPY + EXPUNGE if the server has no MOVE
+		return c.UidMove(seqSet, a.Target)
+	}
+	return fmt.Errorf("unknown post action %q", a.Kind)
+}
+
+func quoteIMAPString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// jobs with a field below this confidence go to the review queue instead of the job list, 0 disables the queue
+var ReviewThreshold = 0.5
+
+// set ReviewThreshold
+func SetReviewThreshold(threshold float64) {
+	ReviewThreshold = threshold
+}
+
+// length of the body snippet shown while reviewing
+const snippetLength = 300
+
+// pendingJob is the body of POST /reviews
+type pendingJob struct {
+	Job
+	Confidence Confidence   `json:"confidence"`
+	Email      emailContext `json:"email"`
+}
+
+type emailContext struct {
+	Subject string `json:"subject"`
+	From    string `json:"from"`
+	Snippet string `json:"snippet"`
+}
+
+func needsReview(job Job) bool {
+	return ReviewThreshold > 0 && job.Confidence.Min() < ReviewThreshold
+}
+
+// the email a job comes from, as the reviewer sees it
+func newEmailContext(email EmailData) emailContext {
+	from := email.From
+	if email.FromName != "" {
+		from = fmt.Sprintf("%s <%s>", email.FromName, email.From)
+	}
+	return emailContext{Subject: email.Subject, From: from, Snippet: snippet(email.Body, snippetLength)}
+}
+
+// processJob saves the job of an email, or queues it for review when the parser is unsure of it.
+// A follow-up that matches a saved job still updates it, the match confirms the company and title
+func processJob(job Job, email emailContext) error {
+	if !needsReview(job) {
+		return saveJob(job)
+	}
+	if isFollowUp(job) {
+		updated, err := updateMatchingJob(job)
+		if err != nil || updated {
+			return err
+		}
+	}
+	return sendJobForReview(job, email)
+}
+
+// POST /reviews with the job, its confidence and the email it comes from
+func sendJobForReview(job Job, email emailContext) error {
+	// placeholders would be approved as they are, the reviewer fills in empty fields
+	if job.Company == "Unknown Company" {
+		job.Company = ""
+	}
+	if job.Title == "Unknown Position" {
+		job.Title = ""
+	}
+
+	pending := pendingJob{Job: job, Confidence: job.Confidence, Email: email}
+
+	resp, err := apiRequest(http.MethodPost, "/reviews", pending)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusCreated:
+		log.Printf("? Job waiting for review (confidence %.1f): %s at %s", job.Confidence.Min(), job.Title, job.Company)
+	case http.StatusConflict:
+		log.Printf("⚠ Job already queued or saved: %s at %s", job.Title, job.Company)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return tokenError(resp.StatusCode, "jobs:write")
+	default:
+		return &statusError{resp.StatusCode, "queueing job: " + resp.Status}
+	}
+	return nil
+}
+
+// start of the text with whitespace collapsed, cut at a word boundary
+func snippet(text string, length int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= length {
+		return text
+	}
+	cut := strings.LastIndexByte(text[:length+1], ' ')
+	if cut <= 0 {
+		cut = length
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+	}
+	return text[:cut] + "…"
+}
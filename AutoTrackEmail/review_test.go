@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseJobConfidence(t *testing.T) {
+	rules, err := ParseRules([]byte(`{"rules": [{"name": "acme", "match": {"from": "@acme\\.example$"},
+		"extract": {"subject": ["(?i)applying to (?P<company>\\w+) as (?P<title>.+)$"]}, "status": "applied"}]}`), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsers := newDefaultParserRegistry(rules, NewCompanyResolver(map[string]string{"hooli.example": "Hooli"}))
+
+	tests := []struct {
+		name  string
+		email EmailData
+		want  Confidence
+	}{
+		{
+			"rule",
+			EmailData{From: "talent@acme.example", Subject: "Thanks for applying to Acme as Backend Developer"},
+			Confidence{Company: confidenceRule, Title: confidenceRule, Status: confidenceRule},
+		},
+		{
+			"dedicated parser",
+			EmailData{From: "no-reply@hire.lever.co", Subject: "Thank you for your application to Initech", Body: "Thanks for applying to Initech. We will review your application for the Data Engineer position and get back to you."},
+			Confidence{Company: confidenceATS, Title: confidenceATS, Status: 0.8},
+		},
+		{
+			"generic phrasing",
+			EmailData{From: "jobs@wayne.example", Subject: "Interview invitation", Body: "Thank you for applying to Wayne Enterprises for the Security Analyst position."},
+			Confidence{Company: 0.6, Title: confidenceGeneric, Status: 0.8}, // company from the sender domain
+		},
+		{
+			"mapped sender",
+			EmailData{From: "careers@hooli.example", Subject: "Application received - Data Scientist position"},
+			Confidence{Company: confidenceRule, Title: 0.5, Status: 0.8},
+		},
+		{
+			"nothing found",
+			EmailData{From: "someone@gmail.com", Subject: "Your application"},
+			Confidence{Company: 0, Title: 0, Status: 0.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := parsers.Parse(tt.email)
+			if job == nil {
+				t.Fatal("no job")
+			}
+			if job.Confidence != tt.want {
+				t.Errorf("confidence = %+v, want %+v (job %q at %q)", job.Confidence, tt.want, job.Title, job.Company)
+			}
+		})
+	}
+}
+
+func TestProcessJobQueuesLowConfidence(t *testing.T) {
+	api := startFakeJobAPI(t)
+	email := EmailData{
+		From:     "someone@gmail.com",
+		FromName: "Sam Recruiter",
+		Subject:  "Your application",
+		Body:     "Hi,\n\n  we got   your application.\n" + strings.Repeat("More text. ", 50),
+	}
+
+	processJob(Job{Company: "Unknown Company", Title: "Unknown Position", Status: "applied", Confidence: Confidence{Status: 0.5}}, newEmailContext(email))
+	processJob(Job{Company: "Acme", Title: "Backend Developer", Status: "applied", Confidence: Confidence{Company: 0.9, Title: 0.9, Status: 0.5}}, newEmailContext(email))
+
+	if got, want := api.requestLog(), "POST /reviews, POST /jobs"; got != want {
+		t.Fatalf("requests = %s, want %s", got, want)
+	}
+	pending := api.reviews[0]
+	if pending.Company != "" || pending.Title != "" {
+		t.Errorf("placeholders sent for review: %q, %q", pending.Company, pending.Title)
+	}
+	if pending.Email.From != "Sam Recruiter <someone@gmail.com>" || pending.Email.Subject != "Your application" {
+		t.Errorf("email = %+v", pending.Email)
+	}
+	if snippet := pending.Email.Snippet; !strings.HasPrefix(snippet, "Hi, we got your application. More text.") || len(snippet) > snippetLength+len("…") {
+		t.Errorf("snippet = %q", snippet)
+	}
+}
+
+func TestProcessJobUpdatesMatchBeforeReview(t *testing.T) {
+	api := startFakeJobAPI(t, JobMatch{ID: 7, Job: Job{Company: "Acme", Title: "Backend Developer", Status: "applied"}, Score: 1})
+
+	followUp := rejection
+	followUp.Confidence = Confidence{Company: 0.3, Title: 0.3, Status: 0.8}
+	processJob(followUp, emailContext{Subject: followUp.Notes})
+
+	if got, want := api.requestLog(), "GET /jobs/match, PUT /jobs/7"; got != want {
+		t.Errorf("requests = %s, want %s", got, want)
+	}
+}
+
+func TestReviewThresholdZeroDisablesQueue(t *testing.T) {
+	previous := ReviewThreshold
+	SetReviewThreshold(0)
+	t.Cleanup(func() { SetReviewThreshold(previous) })
+
+	if needsReview(Job{}) {
+		t.Error("job queued with the review queue disabled")
+	}
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RulesFile is the format of RULES_FILE, YAML or JSON (by extension).
+// Rules let teammates add confirmation formats without touching the Go parsers:
+//
+//	rules:
+//	  - name: acme-careers
+//	    priority: 10                        # higher is tried first, default 0
+//	    match:                              # regexes, every one given must match
+//	      from: '@acme\.example$'
+//	      subject: '(?i)application'
+//	    extract:                            # named groups: company, title, location, status
+//	      subject: ['(?i)applying to (?P<company>.+) for (?P<title>.+)']
+//	      body: ['(?i)location: (?P<location>.+)']
+//	    status: applied                     # used when no status is extracted
+//	  - name: job-alerts
+//	    ignore: true                        # matching emails are not applications
+//	    match: {from: 'jobalerts-noreply@linkedin\.com'}
+//	tests:                                  # checked by `email_watcher validate-rules`
+//	  - email: samples/acme.eml             # relative to the rules file
+//	    rule: acme-careers
+//	    company: Acme
+type RulesFile struct {
+	Rules []Rule     `yaml:"rules" json:"rules"`
+	Tests []RuleTest `yaml:"tests" json:"tests"`
+}
+
+type Rule struct {
+	Name     string      `yaml:"name" json:"name"`
+	Priority int         `yaml:"priority" json:"priority"`
+	Ignore   bool        `yaml:"ignore" json:"ignore"`
+	Match    RuleMatch   `yaml:"match" json:"match"`
+	Extract  RuleExtract `yaml:"extract" json:"extract"`
+	Status   string      `yaml:"status" json:"status"`
+}
+
+type RuleMatch struct {
+	From    string `yaml:"from" json:"from"` // sender address
+	Subject string `yaml:"subject" json:"subject"`
+	Body    string `yaml:"body" json:"body"`
+}
+
+type RuleExtract struct {
+	Subject []string `yaml:"subject" json:"subject"`
+	Body    []string `yaml:"body" json:"body"`
+}
+
+// RuleTest is a sample email and what the rules should get from it, empty fields are not checked
+type RuleTest struct {
+	Email    string `yaml:"email" json:"email"`
+	Rule     string `yaml:"rule" json:"rule"`
+	Ignored  bool   `yaml:"ignored" json:"ignored"`
+	Company  string `yaml:"company" json:"company"`
+	Title    string `yaml:"title" json:"title"`
+	Location string `yaml:"location" json:"location"`
+	Status   string `yaml:"status" json:"status"`
+}
+
+// named groups extraction patterns can use
+var ruleFields = []string{"company", "title", "location", "status"}
+
+// Rules are the compiled rules of a file, highest priority first
+type Rules struct {
+	rules []*compiledRule
+	Tests []RuleTest
+	dir   string // test emails are relative to the rules file
+}
+
+type compiledRule struct {
+	Rule
+	from, subject, body *regexp.Regexp
+	extractSubject      []*regexp.Regexp
+	extractBody         []*regexp.Regexp
+}
+
+// LoadRules reads and compiles a rules file
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format := "yaml"
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = "json"
+	}
+	rules, err := ParseRules(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	rules.dir = filepath.Dir(path)
+	return rules, nil
+}
+
+// ParseRules compiles rules, the error lists every problem found. Unknown keys are errors so typos do not go unnoticed
+func ParseRules(data []byte, format string) (*Rules, error) {
+	var file RulesFile
+	switch format {
+	case "json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&file); err != nil {
+			return nil, err
+		}
+	default:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	names := map[string]bool{}
+	rules := &Rules{Tests: file.Tests}
+	for i, rule := range file.Rules {
+		label := fmt.Sprintf("rule %d", i+1)
+		if rule.Name != "" {
+			label = fmt.Sprintf("rule %q", rule.Name)
+		}
+
+		compiled, ruleErrs := compileRule(rule)
+		if rule.Name == "" {
+			ruleErrs = append(ruleErrs, errors.New("missing name"))
+		} else if names[rule.Name] {
+			ruleErrs = append(ruleErrs, errors.New("duplicate name"))
+		}
+		names[rule.Name] = true
+
+		for _, err := range ruleErrs {
+			errs = append(errs, fmt.Errorf("%s: %w", label, err))
+		}
+		rules.rules = append(rules.rules, compiled)
+	}
+
+	for i, test := range file.Tests {
+		if test.Email == "" {
+			errs = append(errs, fmt.Errorf("test %d: missing email", i+1))
+		}
+		if test.Rule != "" && !names[test.Rule] {
+			errs = append(errs, fmt.Errorf("test %d: unknown rule %q", i+1, test.Rule))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	// stable, so rules with the same priority keep their order in the file
+	slices.SortStableFunc(rules.rules, func(a, b *compiledRule) int { return b.Priority - a.Priority })
+	return rules, nil
+}
+
+func compileRule(rule Rule) (*compiledRule, []error) {
+	var errs []error
+	compile := func(field, expr string) *regexp.Regexp {
+		if expr == "" {
+			return nil
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+		}
+		return re
+	}
+	compileExtract := func(field string, exprs []string) []*regexp.Regexp {
+		var res []*regexp.Regexp
+		for _, expr := range exprs {
+			re := compile(field, expr)
+			if re == nil {
+				continue
+			}
+			var groups []string
+			for _, name := range re.SubexpNames() {
+				if name == "" {
+					continue
+				}
+				if !slices.Contains(ruleFields, name) {
+					errs = append(errs, fmt.Errorf("%s: unknown group %q, use %s", field, name, strings.Join(ruleFields, ", ")))
+				}
+				groups = append(groups, name)
+			}
+			if len(groups) == 0 {
+				errs = append(errs, fmt.Errorf("%s: %q has no named group like (?P<company>...)", field, expr))
+			}
+			res = append(res, re)
+		}
+		return res
+	}
+
+	c := &compiledRule{
+		Rule:           rule,
+		from:           compile("match.from", rule.Match.From),
+		subject:        compile("match.subject", rule.Match.Subject),
+		body:           compile("match.body", rule.Match.Body),
+		extractSubject: compileExtract("extract.subject", rule.Extract.Subject),
+		extractBody:    compileExtract("extract.body", rule.Extract.Body),
+	}
+
+	if rule.Match == (RuleMatch{}) {
+		errs = append(errs, errors.New("match needs at least one of from, subject or body"))
+	}
+	if rule.Status != "" && normalizeStatus(rule.Status) == "" {
+		errs = append(errs, fmt.Errorf("invalid status %q, use applied, interview, offer or rejected", rule.Status))
+	}
+	if rule.Ignore && (len(rule.Extract.Subject) > 0 || len(rule.Extract.Body) > 0 || rule.Status != "") {
+		errs = append(errs, errors.New("an ignore rule cannot extract or set a status"))
+	}
+	return c, errs
+}
+
+// Match returns the highest priority rule that matches the email, nil if none does
+func (r *Rules) Match(email EmailData) *compiledRule {
+	if r == nil {
+		return nil
+	}
+	for _, rule := range r.rules {
+		if rule.matchesEnvelope(email.From, email.Subject) && matchesOptional(rule.body, email.Body) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// the rule that decides from sender and subject alone, before the body is downloaded, nil if none does.
+// Rules that also check the body accept the email for now, the body decides after download.
+// Rules that only check the body are skipped, otherwise every email would be downloaded
+func (r *Rules) matchEnvelopeRule(from, subject string) *compiledRule {
+	if r == nil {
+		return nil
+	}
+	for _, rule := range r.rules {
+		if rule.from == nil && rule.subject == nil {
+			continue
+		}
+		if !rule.matchesEnvelope(from, subject) {
+			continue
+		}
+		if rule.body != nil && rule.Ignore {
+			continue // cannot know if it applies yet
+		}
+		return rule
+	}
+	return nil
+}
+
+func (rule *compiledRule) matchesEnvelope(from, subject string) bool {
+	return matchesOptional(rule.from, from) && matchesOptional(rule.subject, subject)
+}
+
+func matchesOptional(re *regexp.Regexp, text string) bool {
+	return re == nil || re.MatchString(text)
+}
+
+// extract fills what the rule's patterns capture, subject patterns first, and which pattern captured each field
+func (rule *compiledRule) extract(email EmailData) (values, sources map[string]string) {
+	values, sources = map[string]string{}, map[string]string{}
+	for _, field := range ruleFields {
+		if value, source := namedMatchSource(rule.extractSubject, rule.extractBody, email, field); value != "" {
+			values[field] = value
+			sources[field] = fmt.Sprintf("rule %q %s", rule.Name, source)
+		}
+	}
+	return values, sources
+}
+
+// rulesParser runs before the built-in parsers, see ParserRegistry
+type rulesParser struct {
+	rules *Rules
+}
+
+func (p rulesParser) Name() string {
+	return "rules"
+}
+
+func (p rulesParser) Match(email EmailData) bool {
+	return p.rules.Match(email) != nil
+}
+
+func (p rulesParser) Ignore(email EmailData) bool {
+	rule := p.rules.Match(email)
+	return rule != nil && rule.Ignore
+}
+
+func (p rulesParser) Parse(email EmailData) *Job {
+	rule := p.rules.Match(email)
+	if rule == nil || rule.Ignore {
+		return nil
+	}
+
+	values, sources := rule.extract(email)
+	status, statusConfidence, statusSource := normalizeStatus(values["status"]), confidenceRule, sources["status"]
+	if status == "" {
+		status, statusSource = normalizeStatus(rule.Status), fmt.Sprintf("rule %q status", rule.Name)
+	}
+	if status == "" {
+		var source string
+		status, statusConfidence, source = statusFromSubject(email.Subject)
+		statusSource = fmt.Sprintf("rule %q %s", rule.Name, source)
+	}
+
+	// company or title left empty are filled in by the generic parser
+	return &Job{
+		Company:         values["company"],
+		Title:           values["title"],
+		Location:        values["location"],
+		Status:          status,
+		AppliedDate:     email.Date,
+		Notes:           email.Subject,
+		SourceMessageID: email.MessageID,
+		Confidence: Confidence{
+			Company: foundConfidence(values["company"], confidenceRule),
+			Title:   foundConfidence(values["title"], confidenceRule),
+			Status:  statusConfidence,
+		},
+		Sources: Sources{Company: sources["company"], Title: sources["title"], Status: statusSource},
+	}
+}
+
+// map an extracted or configured status to one the server accepts, "" if it is not one
+func normalizeStatus(status string) string {
+	status = strings.ToLower(strings.TrimSpace(status))
+	switch status {
+	case "interviewing":
+		status = "interview"
+	case "rejection", "declined":
+		status = "rejected"
+	}
+	if !slices.Contains(JobStatuses, status) {
+		return ""
+	}
+	return status
+}
+
+// ValidateRules checks the tests of the rules file and prints what the rules get from each sample,
+// a .eml file or a folder of them. It returns false if a test failed
+func ValidateRules(rules *Rules, samples []string, out io.Writer) (bool, error) {
+	parsers := newDefaultParserRegistry(rules, defaultParsers.companies)
+	ok := true
+
+	for _, test := range rules.Tests {
+		path := test.Email
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(rules.dir, path)
+		}
+		email, err := readEmailFile(path)
+		if err != nil {
+			return false, err
+		}
+
+		var problems []string
+		rule := rules.Match(email)
+		ruleName := ""
+		if rule != nil {
+			ruleName = rule.Name
+		}
+		if test.Rule != "" && ruleName != test.Rule {
+			problems = append(problems, fmt.Sprintf("rule = %q, want %q", ruleName, test.Rule))
+		}
+
+		job := parsers.Parse(email)
+		switch {
+		case test.Ignored && job != nil:
+			problems = append(problems, "parsed a job, want the email ignored")
+		case !test.Ignored && job == nil:
+			problems = append(problems, "no job parsed")
+		case job != nil:
+			for _, field := range []struct{ name, got, want string }{
+				{"company", job.Company, test.Company},
+				{"title", job.Title, test.Title},
+				{"location", job.Location, test.Location},
+				{"status", job.Status, test.Status},
+			} {
+				if field.want != "" && field.got != field.want {
+					problems = append(problems, fmt.Sprintf("%s = %q, want %q", field.name, field.got, field.want))
+				}
+			}
+		}
+
+		if len(problems) > 0 {
+			ok = false
+			fmt.Fprintf(out, "FAIL %s: %s\n", test.Email, strings.Join(problems, ", "))
+		} else {
+			fmt.Fprintf(out, "PASS %s\n", test.Email)
+		}
+	}
+
+	for _, sample := range samples {
+		files := []string{sample}
+		if info, err := os.Stat(sample); err == nil && info.IsDir() {
+			files, _ = filepath.Glob(filepath.Join(sample, "*.eml"))
+		}
+		for _, file := range files {
+			email, err := readEmailFile(file)
+			if err != nil {
+				return false, err
+			}
+			fmt.Fprintf(out, "%s: %s\n", file, describeParse(rules, parsers, email))
+		}
+	}
+	return ok, nil
+}
+
+// which rule or parser handled the email and what it got
+func describeParse(rules *Rules, parsers *ParserRegistry, email EmailData) string {
+	by := "parser " + parsers.ParserFor(email).Name()
+	if rule := rules.Match(email); rule != nil {
+		by = fmt.Sprintf("rule %q", rule.Name)
+	}
+
+	job := parsers.Parse(email)
+	if job == nil {
+		return by + ", no job (ignored or nothing found)"
+	}
+	return fmt.Sprintf("%s, company=%q title=%q location=%q status=%q", by, job.Company, job.Title, job.Location, job.Status)
+}
+
+func readEmailFile(path string) (EmailData, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return EmailData{}, err
+	}
+	defer f.Close()
+
+	email, err := readEmail(f, time.Now())
+	if err != nil {
+		return EmailData{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return email, nil
+}
+
+// rules of RULES_FILE, also used to pick which emails to download
+var jobRules *Rules
+
+// UseRules makes the rules part of the job email filter and puts them in front of the built-in parsers
+func UseRules(rules *Rules) {
+	jobRules = rules
+	defaultParsers = newDefaultParserRegistry(rules, defaultParsers.companies)
+}
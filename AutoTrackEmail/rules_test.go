@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseRulesReportsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"missing name", "rules:\n  - match: {subject: 'x'}", "missing name"},
+		{"duplicate name", "rules:\n  - {name: a, match: {subject: x}}\n  - {name: a, match: {subject: y}}", "duplicate name"},
+		{"no match", "rules:\n  - name: a", "match needs at least one"},
+		{"invalid regex", "rules:\n  - {name: a, match: {subject: '(oops'}}", "match.subject"},
+		{"unknown group", "rules:\n  - {name: a, match: {subject: x}, extract: {subject: ['(?P<salary>.+)']}}", `unknown group "salary"`},
+		{"no named group", "rules:\n  - {name: a, match: {subject: x}, extract: {body: ['(.+)']}}", "has no named group"},
+		{"invalid status", "rules:\n  - {name: a, match: {subject: x}, status: hired}", `invalid status "hired"`},
+		{"ignore with extract", "rules:\n  - {name: a, ignore: true, match: {subject: x}, extract: {subject: ['(?P<title>.+)']}}", "ignore rule cannot extract"},
+		{"typo in key", "rules:\n  - {name: a, match: {subjet: x}}", "subjet"},
+		{"unknown test rule", "rules:\n  - {name: a, match: {subject: x}}\ntests:\n  - {email: a.eml, rule: b}", `unknown rule "b"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRules([]byte(tt.yaml), "yaml")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRulesPriorityAndIgnore(t *testing.T) {
+	rules, err := ParseRules([]byte(`{
+		"rules": [
+			{"name": "catch-all", "match": {"from": "@acme\\.example$"},
+			 "extract": {"subject": ["(?i)applying to (?P<company>\\w+)"]}},
+			{"name": "acme-interview", "priority": 5, "match": {"from": "@acme\\.example$", "subject": "(?i)interview"},
+			 "extract": {"subject": ["(?i)interview for (?P<title>.+?) in (?P<location>.+)$"]}, "status": "interview"},
+			{"name": "acme-newsletter", "priority": 10, "ignore": true, "match": {"subject": "(?i)newsletter"}}
+		]
+	}`), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsers := newDefaultParserRegistry(rules, NewCompanyResolver(nil))
+
+	interview := EmailData{From: "talent@acme.example", Subject: "Interview for Backend Developer in Berlin"}
+	if rule := rules.Match(interview); rule == nil || rule.Name != "acme-interview" {
+		t.Fatalf("matched %v, want the higher priority acme-interview", rule)
+	}
+	job := parsers.Parse(interview)
+	if job == nil || job.Title != "Backend Developer" || job.Location != "Berlin" || job.Status != "interview" {
+		t.Errorf("got %+v", job)
+	}
+
+	applied := EmailData{From: "talent@acme.example", Subject: "Thanks for applying to Acme"}
+	if job := parsers.Parse(applied); job == nil || job.Company != "Acme" || job.Status != "applied" {
+		t.Errorf("got %+v, want company Acme from catch-all", job)
+	}
+
+	newsletter := EmailData{From: "talent@acme.example", Subject: "Acme newsletter: your application tips"}
+	if job := parsers.Parse(newsletter); job != nil {
+		t.Errorf("ignored email parsed as %+v", job)
+	}
+
+	for _, tt := range []struct {
+		from, subject   string
+		accept, decided bool
+	}{
+		{"talent@acme.example", "Hello", true, true},                         // rule widens the keyword filter
+		{"news@other.example", "Weekly newsletter application", false, true}, // ignore rule beats keywords
+		{"jobs@other.example", "Your application", false, false},             // keywords decide
+	} {
+		rule := rules.matchEnvelopeRule(tt.from, tt.subject)
+		decided := rule != nil
+		accept := decided && !rule.Ignore
+		if accept != tt.accept || decided != tt.decided {
+			t.Errorf("matchEnvelopeRule(%q, %q) accepts %v, decides %v, want %v, %v", tt.from, tt.subject, accept, decided, tt.accept, tt.decided)
+		}
+	}
+}
+
+func TestExampleRulesPassTheirTests(t *testing.T) {
+	rules, err := LoadRules("rules.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	ok, err := ValidateRules(rules, []string{"testdata/emails/ashby_thanks.eml"}, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Errorf("example rules fail their tests:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), `ashby_thanks.eml: parser ashby, company="Pied Piper"`) {
+		t.Errorf("sample output missing:\n%s", out.String())
+	}
+}
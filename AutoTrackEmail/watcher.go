@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/emersion/go-imap/client"
+)
+
+// Watcher keeps an IMAP connection open and processes new mail as it arrives.
+// It waits with IMAP IDLE (or polls if the server has no IDLE) and
+// reconnects with exponential backoff when the connection drops
+type Watcher struct {
+	Connect      func() (*client.Client, error)
+	Process      func(c *client.Client) error // fetch and handle new emails
+	PollInterval time.Duration                // used when the server does not support IDLE
+	MinBackoff   time.Duration                // first wait before reconnecting
+	MaxBackoff   time.Duration                // longest wait before reconnecting
+}
+
+var errConnectionClosed = errors.New("connection closed by server")
+
+// Run watches until ctx is cancelled, it only returns ctx's error
+func (w *Watcher) Run(ctx context.Context) error {
+	backoff := w.MinBackoff
+	for {
+		processed, err := w.session(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if processed {
+			backoff = w.MinBackoff // the mailbox was read, start over
+		}
+
+		log.Printf("Watch interrupted: %v, reconnecting in %s", err, backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, w.MaxBackoff)
+	}
+}
+
+// one connection: process what is already there, then wait for new mail until the connection fails.
+// processed is false when the session ended before Process first succeeded, e.g. for a mailbox
+// that does not exist, so the next attempt waits longer instead of logging in again right away
+func (w *Watcher) session(ctx context.Context) (processed bool, err error) {
+	c, err := w.Connect()
+	if err != nil {
+		return false, err
+	}
+	defer c.Logout()
+
+	// the client blocks if updates are not read, so drain them in the background
+	updates := make(chan client.Update, 64)
+	newMail := make(chan struct{}, 1)
+	sessionDone := make(chan struct{})
+	defer close(sessionDone)
+	c.Updates = updates
+	go func() {
+		for {
+			select {
+			case update := <-updates:
+				if _, ok := update.(*client.MailboxUpdate); ok {
+					select {
+					case newMail <- struct{}{}:
+					default: // already signalled
+					}
+				}
+			case <-sessionDone:
+				return
+			}
+		}
+	}()
+
+	if err := w.Process(c); err != nil {
+		return false, err
+	}
+	known := selectedMessages(c)
+
+	for {
+		stop := make(chan struct{})
+		idleDone := make(chan error, 1)
+		go func() {
+			idleDone <- c.Idle(stop, &client.IdleOptions{PollInterval: w.PollInterval})
+		}()
+
+		select {
+		case <-ctx.Done():
+			close(stop)
+			<-idleDone
+			return true, ctx.Err()
+		case err := <-idleDone:
+			if err == nil {
+				err = errConnectionClosed
+			}
+			return true, err
+		case <-c.LoggedOut():
+			return true, errConnectionClosed
+		case <-newMail:
+			close(stop)
+			if err := <-idleDone; err != nil {
+				return true, err
+			}
+			// SELECT in Process and expunges also send updates, only a higher count is new mail
+			if count := selectedMessages(c); count <= known {
+				known = count
+				continue
+			}
+			log.Println("New mail arrived")
+			if err := w.Process(c); err != nil {
+				return true, err
+			}
+			known = selectedMessages(c)
+		}
+	}
+}
+
+// number of messages in the selected mailbox, as last reported by the server
+func selectedMessages(c *client.Client) uint32 {
+	if mbox := c.Mailbox(); mbox != nil {
+		return mbox.Messages
+	}
+	return 0
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend"
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/server"
+)
+
+// memory backend that can announce new mail to IDLE clients
+type updatingBackend struct {
+	*memory.Backend
+	updates chan backend.Update
+}
+
+func (b *updatingBackend) Updates() <-chan backend.Update {
+	return b.updates
+}
+
+// start an in-memory IMAP server, login is username/password.
+// configure can change the server before it starts serving
+func startTestIMAPServer(t *testing.T, configure ...func(*server.Server)) (string, *updatingBackend) {
+	t.Helper()
+
+	be := &updatingBackend{Backend: memory.New(), updates: make(chan backend.Update, 16)}
+	s := server.New(be)
+	s.AllowInsecureAuth = true
+	for _, f := range configure {
+		f(s)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	t.Cleanup(func() { s.Close() })
+
+	return l.Addr().String(), be
+}
+
+// add an unread message to INBOX and notify IDLE clients
+func deliverTestEmail(t *testing.T, be *updatingBackend, subject string) {
+	t.Helper()
+
+	status := addTestEmail(t, be, subject)
+	be.updates <- &backend.MailboxUpdate{Update: backend.NewUpdate("username", "INBOX"), MailboxStatus: status}
+}
+
+// add an unread message to INBOX without notifying clients.
+// go-imap's server races with SELECT when it sends updates, so tests that do not IDLE use this
+func addTestEmail(t *testing.T, be *updatingBackend, subject string) *imap.MailboxStatus {
+	t.Helper()
+
+	user, err := be.Login(nil, "username", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mbox, err := user.GetMailbox("INBOX")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := fmt.Sprintf("From: jobs@example.com\r\n"+
+		"To: me@example.com\r\n"+
+		"Subject: %s\r\n"+
+		"Date: %s\r\n"+
+		"Message-ID: <%d@example.com>\r\n"+
+		"Content-Type: text/plain\r\n"+
+		"\r\n"+
+		"Thank you for applying.", subject, time.Now().Format(time.RFC1123Z), time.Now().UnixNano())
+	if err := mbox.CreateMessage(nil, time.Now(), bytes.NewBufferString(body)); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := mbox.Status([]imap.StatusItem{imap.StatusMessages})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
+
+func dialTestIMAPServer(addr string) (*client.Client, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, err
+	}
+	return ConnectIMAP(IMAPConfig{Host: host, Port: p, Security: SecurityNone, Mailbox: "INBOX"}, PasswordAuth("username", "password"))
+}
+
+// collects the subjects of fetched job emails
+type emailCollector struct {
+	mu         sync.Mutex
+	checkpoint Checkpoint
+	subjects   map[string]int // how many times each email was fetched
+	runs       int
+}
+
+func newEmailCollector() *emailCollector {
+	return &emailCollector{subjects: map[string]int{}}
+}
+
+func (ec *emailCollector) process(c *client.Client) error {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	emails, cp, err := FetchNewEmails(c, "INBOX", ec.checkpoint)
+	if err != nil {
+		return err
+	}
+	ec.checkpoint = cp
+	ec.runs++
+	for _, email := range emails {
+		ec.subjects[email.Subject]++
+	}
+	return nil
+}
+
+func (ec *emailCollector) processRuns() int {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+	return ec.runs
+}
+
+func (ec *emailCollector) waitForRuns(t *testing.T, runs int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for ec.processRuns() < runs {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d runs", runs)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (ec *emailCollector) waitFor(t *testing.T, subject string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		ec.mu.Lock()
+		count := ec.subjects[subject]
+		ec.mu.Unlock()
+		if count > 1 {
+			t.Fatalf("email %q was fetched %d times", subject, count)
+		}
+		if count == 1 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for email %q", subject)
+}
+
+func runTestWatcher(t *testing.T, w *Watcher) (cancel func()) {
+	t.Helper()
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	return func() {
+		cancelCtx()
+		select {
+		case err := <-done:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("Run returned %v, want context.Canceled", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("watcher did not stop after cancel")
+		}
+	}
+}
+
+func TestWatcherProcessesNewMailWithIdle(t *testing.T) {
+	addr, be := startTestIMAPServer(t)
+	collector := newEmailCollector()
+
+	stop := runTestWatcher(t, &Watcher{
+		Connect:      func() (*client.Client, error) { return dialTestIMAPServer(addr) },
+		Process:      collector.process,
+		PollInterval: time.Hour, // make sure IDLE, not polling, delivers the mail
+		MinBackoff:   10 * time.Millisecond,
+		MaxBackoff:   50 * time.Millisecond,
+	})
+	defer stop()
+
+	// go-imap's server races when an update arrives during login, deliver once the watcher is idling
+	collector.waitForRuns(t, 1)
+	deliverTestEmail(t, be, "Your application was sent to Acme")
+	collector.waitFor(t, "Your application was sent to Acme")
+
+	deliverTestEmail(t, be, "Thank you for your application to Globex")
+	collector.waitFor(t, "Thank you for your application to Globex")
+
+	// the EXISTS sent by our own SELECT must not wake the watcher again
+	runs := collector.processRuns()
+	time.Sleep(200 * time.Millisecond)
+	if got := collector.processRuns(); got != runs {
+		t.Errorf("mailbox processed %d more times without new mail", got-runs)
+	}
+}
+
+func TestWatcherReconnectsWithBackoff(t *testing.T) {
+	addr, be := startTestIMAPServer(t)
+	collector := newEmailCollector()
+
+	var mu sync.Mutex
+	attempts := 0
+	connect := func() (*client.Client, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts <= 2 {
+			return nil, errors.New("network is down")
+		}
+		return dialTestIMAPServer(addr)
+	}
+
+	deliverTestEmail(t, be, "Application received: Backend Developer")
+
+	stop := runTestWatcher(t, &Watcher{
+		Connect:      connect,
+		Process:      collector.process,
+		PollInterval: time.Hour,
+		MinBackoff:   10 * time.Millisecond,
+		MaxBackoff:   50 * time.Millisecond,
+	})
+	defer stop()
+
+	collector.waitFor(t, "Application received: Backend Developer")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts != 3 {
+		t.Errorf("connected after %d attempts, want 3", attempts)
+	}
+}
+
+func TestWatcherBacksOffWhenProcessFails(t *testing.T) {
+	addr, _ := startTestIMAPServer(t)
+
+	var mu sync.Mutex
+	var connects []time.Time
+	connect := func() (*client.Client, error) {
+		mu.Lock()
+		connects = append(connects, time.Now())
+		mu.Unlock()
+		return dialTestIMAPServer(addr)
+	}
+
+	const minBackoff = 20 * time.Millisecond
+	stop := runTestWatcher(t, &Watcher{
+		Connect:      connect,
+		Process:      func(*client.Client) error { return errors.New("mailbox does not exist") },
+		PollInterval: time.Hour,
+		MinBackoff:   minBackoff,
+		MaxBackoff:   time.Second,
+	})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(connects)
+		mu.Unlock()
+		if n >= 5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d connects", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := minBackoff
+	for i := 1; i < 5; i++ {
+		if gap := connects[i].Sub(connects[i-1]); gap < want {
+			t.Errorf("connect %d came %s after the previous one, want at least %s", i+1, gap, want)
+		}
+		want *= 2
+	}
+}
+
+func TestWatcherStopsWhenCancelled(t *testing.T) {
+	addr, _ := startTestIMAPServer(t)
+
+	stop := runTestWatcher(t, &Watcher{
+		Connect:      func() (*client.Client, error) { return dialTestIMAPServer(addr) },
+		Process:      func(*client.Client) error { return nil },
+		PollInterval: time.Hour,
+		MinBackoff:   10 * time.Millisecond,
+		MaxBackoff:   50 * time.Millisecond,
+	})
+
+	time.Sleep(100 * time.Millisecond) // let it connect and start idling
+	stop()
+}
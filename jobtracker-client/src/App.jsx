@@ -1,22 +1,33 @@
 import React, { useState, useEffect } from 'react';
-import { Plus, Edit, Trash2, Briefcase, Calendar, Building, FileText } from 'lucide-react';
+import { Plus, Edit, Trash2, Briefcase, Calendar, Building, MapPin, FileText, LogOut, Mail, Check, X } from 'lucide-react';
+import Login from './Login';
+import { SERVER_URL, authFetch, fetchStatuses, isLoggedIn, logout, responseError } from './api';
+
+// used until GET /statuses answers
+const DEFAULT_STATUSES = { statuses: ['applied', 'interview', 'offer', 'rejected'], transitions: {} };
 
 const JobTracker = () => {
+  const [loggedIn, setLoggedIn] = useState(isLoggedIn());
   const [jobs, setJobs] = useState([]);
   const [isModalOpen, setIsModalOpen] = useState(false);
   const [editingJob, setEditingJob] = useState(null);
+  const [reviews, setReviews] = useState([]); // jobs parsed from emails with low confidence
+  const [reviewing, setReviewing] = useState(null); // pending job being approved in the modal
+  const [statuses, setStatuses] = useState(DEFAULT_STATUSES);
+  const [duplicate, setDuplicate] = useState(null); // { id, canMerge } of the job a 409 pointed at
   const [loading, setLoading] = useState(false);
   const [error, setError] = useState(null);
   const [formData, setFormData] = useState({
     company: '',
     title: '',
+    location: '',
     status: 'applied',
     applied_date: '', // Fixed typo: was 'appled_date'
     notes: ''
   });
 
-  // API base URL - adjust this to your Go server
-  const API_BASE = 'http://localhost:8080/jobs';
+  const API_BASE = `${SERVER_URL}/jobs`;
+  const REVIEWS_BASE = `${SERVER_URL}/reviews`;
 
   // Fetch all jobs
   const fetchJobs = async () => {
@@ -24,15 +35,21 @@ const JobTracker = () => {
       setLoading(true);
       setError(null);
       
-      const response = await fetch(API_BASE, {
+      const response = await authFetch(API_BASE, {
         method: "GET",
         headers: {
           "Content-Type": "application/json"
         }
       });
+
+      // session expired and could not be refreshed
+      if (response.status === 401) {
+        handleLogout();
+        return;
+      }
       
       if (!response.ok) {
-        throw new Error(`HTTP error! status: ${response.status}`);
+        throw await responseError(response);
       }
       
       const data = await response.json();
@@ -45,6 +62,21 @@ const JobTracker = () => {
     }
   };
 
+  // Fetch the jobs waiting for review
+  const fetchReviews = async () => {
+    try {
+      const response = await authFetch(REVIEWS_BASE);
+      if (!response.ok) {
+        throw await responseError(response);
+      }
+      const data = await response.json();
+      setReviews(data || []);
+    } catch (error) {
+      console.error('Error fetching reviews:', error);
+      setError('Failed to fetch jobs to review: ' + error.message);
+    }
+  };
+
   // Create or update job
   const saveJob = async () => {
     if (!formData.company.trim() || !formData.title.trim()) {
@@ -55,31 +87,53 @@ const JobTracker = () => {
     try {
       setLoading(true);
       setError(null);
+      setDuplicate(null);
       
-      if (editingJob) {
+      if (reviewing) {
+        // Approve a pending job with the corrected fields
+        const response = await authFetch(`${REVIEWS_BASE}/${reviewing.id}/approve`, {
+          method: 'POST',
+          headers: { 'Content-Type': 'application/json' },
+          body: JSON.stringify(formData)
+        });
+
+        if (response.status === 409) {
+          throw await duplicateError(response, false);
+        }
+        if (!response.ok) {
+          throw await responseError(response);
+        }
+
+        const newJob = await response.json();
+        setJobs([...jobs, newJob]);
+        setReviews(reviews.filter(pending => pending.id !== reviewing.id));
+      } else if (editingJob) {
         // Update existing job
-        const response = await fetch(`${API_BASE}/${editingJob.id}`, {
+        const response = await authFetch(`${API_BASE}/${editingJob.id}`, {
           method: 'PUT',
           headers: { 'Content-Type': 'application/json' },
           body: JSON.stringify(formData)
         });
         
         if (!response.ok) {
-          throw new Error(`HTTP error! status: ${response.status}`);
+          throw await responseError(response);
         }
         
         const updatedJob = await response.json();
         setJobs(jobs.map(job => job.id === editingJob.id ? updatedJob : job));
       } else {
         // Create new job
-        const response = await fetch(API_BASE, {
+        const response = await authFetch(API_BASE, {
           method: 'POST',
           headers: { 'Content-Type': 'application/json' },
           body: JSON.stringify(formData)
         });
         
+        if (response.status === 409) {
+          throw await duplicateError(response, true);
+        }
         if (!response.ok) {
-          throw new Error(`HTTP error! status: ${response.status}`);
+          throw await responseError(response);
         }
         
         const newJob = await response.json();
@@ -95,6 +149,57 @@ const JobTracker = () => {
     }
   };
 
+  // A 409 from POST /jobs or an approval carries the id of the existing job,
+  // remember it so the modal can open that job (or merge into it, for a new job)
+  const duplicateError = async (response, canMerge) => {
+    const data = await response.json().catch(() => ({}));
+    if (data.id) {
+      setDuplicate({ id: data.id, canMerge });
+    }
+    return new Error(data.error || 'Job already exists');
+  };
+
+  // Open the job a duplicate was found for
+  const openDuplicate = () => {
+    const existing = jobs.find(job => job.id === duplicate.id);
+    if (!existing) {
+      setError(`Job ${duplicate.id} is not loaded, refresh the page to see it`);
+      return;
+    }
+    setError(null);
+    setDuplicate(null);
+    openEditModal(existing);
+  };
+
+  // Merge the form into the existing job with POST /jobs?upsert=true
+  const mergeDuplicate = async () => {
+    try {
+      setLoading(true);
+      setError(null);
+
+      const response = await authFetch(`${API_BASE}?upsert=true`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(formData)
+      });
+
+      if (!response.ok) {
+        throw await responseError(response);
+      }
+
+      const merged = await response.json();
+      setJobs(jobs.some(job => job.id === merged.id)
+        ? jobs.map(job => job.id === merged.id ? merged : job)
+        : [...jobs, merged]);
+      closeModal();
+    } catch (error) {
+      console.error('Error merging job:', error);
+      setError('Failed to merge job: ' + error.message);
+    } finally {
+      setLoading(false);
+    }
+  };
+
   // Delete job
   const deleteJob = async (id) => {
     if (window.confirm('Are you sure you want to delete this job?')) {
@@ -102,10 +207,10 @@ const JobTracker = () => {
         setLoading(true);
         setError(null);
         
-        const response = await fetch(`${API_BASE}/${id}`, { method: 'DELETE' });
+        const response = await authFetch(`${API_BASE}/${id}`, { method: 'DELETE' });
         
         if (!response.ok) {
-          throw new Error(`HTTP error! status: ${response.status}`);
+          throw await responseError(response);
         }
         
         setJobs(jobs.filter(job => job.id !== id));
@@ -118,12 +223,51 @@ const JobTracker = () => {
     }
   };
 
+  // Drop a pending job
+  const rejectReview = async (id) => {
+    try {
+      setLoading(true);
+      setError(null);
+
+      const response = await authFetch(`${REVIEWS_BASE}/${id}/reject`, { method: 'POST' });
+
+      if (!response.ok) {
+        throw await responseError(response);
+      }
+
+      setReviews(reviews.filter(pending => pending.id !== id));
+    } catch (error) {
+      console.error('Error rejecting job:', error);
+      setError('Failed to reject job: ' + error.message);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  // Open modal for approving a pending job, its fields can be corrected first
+  const openReviewModal = (pending) => {
+    setDuplicate(null);
+    setEditingJob(null);
+    setReviewing(pending);
+    setFormData({
+      company: pending.company,
+      title: pending.title,
+      location: pending.location || '',
+      status: pending.status,
+      applied_date: pending.applied_date || '',
+      notes: pending.notes
+    });
+    setIsModalOpen(true);
+  };
+
   // Open modal for editing
   const openEditModal = (job) => {
+    setReviewing(null);
     setEditingJob(job);
     setFormData({
       company: job.company,
       title: job.title,
+      location: job.location || '',
       status: job.status,
       applied_date: job.applied_date || '',
       notes: job.notes
@@ -133,10 +277,13 @@ const JobTracker = () => {
 
   // Open modal for creating
   const openCreateModal = () => {
+    setDuplicate(null);
+    setReviewing(null);
     setEditingJob(null);
     setFormData({
       company: '',
       title: '',
+      location: '',
       status: 'applied',
       applied_date: new Date().toISOString().split('T')[0], // today's date
       notes: ''
@@ -148,9 +295,12 @@ const JobTracker = () => {
   const closeModal = () => {
     setIsModalOpen(false);
     setEditingJob(null);
+    setReviewing(null);
+    setDuplicate(null);
     setFormData({
       company: '',
       title: '',
+      location: '',
       status: 'applied',
       applied_date: new Date().toISOString().split('T')[0], // today's date
       notes: ''
@@ -158,6 +308,14 @@ const JobTracker = () => {
     setError(null);
   };
 
+  // Statuses the job being edited can move to, any status for a new job.
+  // The server refuses the others with 422
+  const allowedStatus = (status) => {
+    const current = editingJob?.status;
+    if (!current || current === status || !statuses.transitions[current]) return true;
+    return statuses.transitions[current].includes(status);
+  };
+
   // Status colors
   const getStatusColor = (status) => {
     switch (status) {
@@ -169,11 +327,33 @@ const JobTracker = () => {
     }
   };
 
-  // Load jobs on component mount
+  // Log out and go back to the login screen
+  const handleLogout = () => {
+    logout();
+    setJobs([]);
+    setReviews([]);
+    setLoggedIn(false);
+  };
+
+  // Load jobs once logged in
+  useEffect(() => {
+    if (loggedIn) {
+      fetchJobs();
+      fetchReviews();
+    }
+  }, [loggedIn]);
+
+  // Load the statuses the server accepts
   useEffect(() => {
-    fetchJobs();
+    fetchStatuses()
+      .then(setStatuses)
+      .catch((error) => console.error('Error fetching statuses:', error));
   }, []);
 
+  if (!loggedIn) {
+    return <Login onLogin={() => setLoggedIn(true)} />;
+  }
+
   return (
     <div className="min-h-screen bg-gray-50 py-8">
       <div className="max-w-6xl mx-auto px-4">
@@ -183,14 +363,23 @@ const JobTracker = () => {
             <Briefcase className="h-8 w-8 text-blue-600" />
             <h1 className="text-3xl font-bold text-gray-900">Job Tracker</h1>
           </div>
-          <button
-            onClick={openCreateModal}
-            disabled={loading}
-            className="flex items-center gap-2 bg-blue-600 text-white px-4 py-2 rounded-lg hover:bg-blue-700 transition-colors disabled:opacity-50"
-          >
-            <Plus className="h-4 w-4" />
-            Add Job
-          </button>
+          <div className="flex items-center gap-2">
+            <button
+              onClick={openCreateModal}
+              disabled={loading}
+              className="flex items-center gap-2 bg-blue-600 text-white px-4 py-2 rounded-lg hover:bg-blue-700 transition-colors disabled:opacity-50"
+            >
+              <Plus className="h-4 w-4" />
+              Add Job
+            </button>
+            <button
+              onClick={handleLogout}
+              className="flex items-center gap-2 text-gray-600 px-4 py-2 rounded-lg hover:bg-gray-200 transition-colors"
+            >
+              <LogOut className="h-4 w-4" />
+              Log out
+            </button>
+          </div>
         </div>
 
         {/* Error Message */}
@@ -214,6 +403,49 @@ const JobTracker = () => {
           </div>
         )}
 
+        {/* Review Queue */}
+        {reviews.length > 0 && (
+          <div className="mb-8">
+            <h2 className="text-xl font-semibold text-gray-900 mb-4">Needs review ({reviews.length})</h2>
+            <div className="space-y-3">
+              {reviews.map((pending) => (
+                <div key={pending.id} className="bg-amber-50 border border-amber-200 rounded-lg p-4 flex items-start justify-between gap-4">
+                  <div className="flex-1 min-w-0">
+                    <h3 className="font-semibold text-gray-900">
+                      {pending.title || 'Unknown position'} at {pending.company || 'unknown company'}
+                    </h3>
+                    <div className="flex items-center gap-2 text-sm text-gray-600 mt-1">
+                      <Mail className="h-4 w-4 shrink-0" />
+                      <span className="truncate">{pending.email.subject} ({pending.email.from})</span>
+                    </div>
+                    {pending.email.snippet && (
+                      <p className="text-sm text-gray-500 mt-1 line-clamp-2">{pending.email.snippet}</p>
+                    )}
+                  </div>
+                  <div className="flex gap-2">
+                    <button
+                      onClick={() => openReviewModal(pending)}
+                      disabled={loading}
+                      title="Approve"
+                      className="p-2 text-gray-400 hover:text-green-600 transition-colors disabled:opacity-50"
+                    >
+                      <Check className="h-4 w-4" />
+                    </button>
+                    <button
+                      onClick={() => rejectReview(pending.id)}
+                      disabled={loading}
+                      title="Reject"
+                      className="p-2 text-gray-400 hover:text-red-600 transition-colors disabled:opacity-50"
+                    >
+                      <X className="h-4 w-4" />
+                    </button>
+                  </div>
+                </div>
+              ))}
+            </div>
+          </div>
+        )}
+
         {/* Jobs Grid */}
         <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-3 gap-6">
           {jobs.map((job) => (
@@ -247,6 +479,13 @@ const JobTracker = () => {
                 </div>
               </div>
 
+              {job.location && (
+                <div className="flex items-center gap-2 text-sm text-gray-500 mb-1">
+                  <MapPin className="h-4 w-4" />
+                  <span>{job.location}</span>
+                </div>
+              )}
+
               {(job.applied_date) && (
                 <div className="flex items-center gap-2 text-sm text-gray-500 mb-3">
                   <Calendar className="h-4 w-4" />
@@ -284,12 +523,32 @@ const JobTracker = () => {
         <div className="fixed inset-0 bg-black bg-opacity-50 flex items-center justify-center p-4 z-50">
           <div className="bg-white rounded-lg max-w-md w-full p-6">
             <h2 className="text-xl font-semibold mb-4">
-              {editingJob ? 'Edit Job' : 'Add New Job'}
+              {reviewing ? 'Review Job' : (editingJob ? 'Edit Job' : 'Add New Job')}
             </h2>
 
+            {reviewing && (
+              <div className="bg-gray-50 border border-gray-200 rounded px-3 py-2 mb-4 text-sm text-gray-600">
+                <p className="font-medium text-gray-800">{reviewing.email.subject}</p>
+                <p className="mb-1">{reviewing.email.from}</p>
+                <p className="line-clamp-4">{reviewing.email.snippet}</p>
+              </div>
+            )}
+
             {error && (
               <div className="bg-red-100 border border-red-400 text-red-700 px-3 py-2 rounded mb-4 text-sm">
                 {error}
+                {duplicate && (
+                  <div className="flex gap-3 mt-2">
+                    <button onClick={openDuplicate} disabled={loading} className="underline hover:text-red-900 disabled:opacity-50">
+                      Open existing job
+                    </button>
+                    {duplicate.canMerge && (
+                      <button onClick={mergeDuplicate} disabled={loading} className="underline hover:text-red-900 disabled:opacity-50">
+                        Merge into it
+                      </button>
+                    )}
+                  </div>
+                )}
               </div>
             )}
 
@@ -320,6 +579,18 @@ const JobTracker = () => {
                 />
               </div>
 
+              <div>
+                <label className="block text-sm font-medium text-gray-700 mb-1">
+                  Location
+                </label>
+                <input
+                  type="text"
+                  value={formData.location}
+                  onChange={(e) => setFormData({ ...formData, location: e.target.value })}
+                  className="w-full border border-gray-300 rounded-md px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500"
+                />
+              </div>
+
               <div>
                 <label className="block text-sm font-medium text-gray-700 mb-1">
                   Status
@@ -329,10 +600,11 @@ const JobTracker = () => {
                   onChange={(e) => setFormData({ ...formData, status: e.target.value })}
                   className="w-full border border-gray-300 rounded-md px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500"
                 >
-                  <option value="applied">Applied</option>
-                  <option value="interview">Interview</option>
-                  <option value="offer">Offer</option>
-                  <option value="rejected">Rejected</option>
+                  {statuses.statuses.map((status) => (
+                    <option key={status} value={status} disabled={!allowedStatus(status)}>
+                      {status.charAt(0).toUpperCase() + status.slice(1)}
+                    </option>
+                  ))}
                 </select>
               </div>
 
@@ -366,7 +638,7 @@ const JobTracker = () => {
                   disabled={loading}
                   className="flex-1 bg-blue-600 text-white py-2 rounded-md hover:bg-blue-700 transition-colors disabled:opacity-50"
                 >
-                  {loading ? 'Saving...' : (editingJob ? 'Update' : 'Create')}
+                  {loading ? 'Saving...' : (reviewing ? 'Approve' : (editingJob ? 'Update' : 'Create'))}
                 </button>
                 <button
                   onClick={closeModal}
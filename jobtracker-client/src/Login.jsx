@@ -0,0 +1,87 @@
+import React, { useState } from 'react';
+import { Briefcase } from 'lucide-react';
+import { login, register } from './api';
+
+const Login = ({ onLogin }) => {
+  const [isRegistering, setIsRegistering] = useState(false);
+  const [email, setEmail] = useState('');
+  const [password, setPassword] = useState('');
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState(null);
+
+  const submit = async (e) => {
+    e.preventDefault();
+    try {
+      setLoading(true);
+      setError(null);
+      if (isRegistering) {
+        await register(email, password);
+      } else {
+        await login(email, password);
+      }
+      onLogin();
+    } catch (error) {
+      setError(error.message);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  return (
+    <div className="min-h-screen bg-gray-50 flex items-center justify-center px-4">
+      <form onSubmit={submit} className="bg-white rounded-lg shadow p-6 w-full max-w-sm space-y-4">
+        <div className="flex items-center gap-3">
+          <Briefcase className="h-8 w-8 text-blue-600" />
+          <h1 className="text-2xl font-bold text-gray-900">Job Tracker</h1>
+        </div>
+
+        {error && (
+          <div className="bg-red-100 border border-red-400 text-red-700 px-4 py-3 rounded">
+            {error}
+          </div>
+        )}
+
+        <div>
+          <label className="block text-sm font-medium text-gray-700 mb-1">Email</label>
+          <input
+            type="email"
+            value={email}
+            onChange={(e) => setEmail(e.target.value)}
+            className="w-full border border-gray-300 rounded-md px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500"
+            required
+          />
+        </div>
+
+        <div>
+          <label className="block text-sm font-medium text-gray-700 mb-1">Password</label>
+          <input
+            type="password"
+            value={password}
+            onChange={(e) => setPassword(e.target.value)}
+            className="w-full border border-gray-300 rounded-md px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500"
+            minLength={8}
+            required
+          />
+        </div>
+
+        <button
+          type="submit"
+          disabled={loading}
+          className="w-full bg-blue-600 text-white py-2 rounded-md hover:bg-blue-700 transition-colors disabled:opacity-50"
+        >
+          {loading ? 'Please wait...' : (isRegistering ? 'Create account' : 'Log in')}
+        </button>
+
+        <button
+          type="button"
+          onClick={() => setIsRegistering(!isRegistering)}
+          className="w-full text-sm text-blue-600 hover:underline"
+        >
+          {isRegistering ? 'Already have an account? Log in' : 'No account yet? Register'}
+        </button>
+      </form>
+    </div>
+  );
+};
+
+export default Login;
@@ -0,0 +1,83 @@
+// API base URL - adjust this to your Go server
+export const SERVER_URL = 'http://localhost:8080';
+
+const ACCESS_TOKEN_KEY = 'jobtracker.accessToken';
+const REFRESH_TOKEN_KEY = 'jobtracker.refreshToken';
+
+export const isLoggedIn = () => Boolean(localStorage.getItem(ACCESS_TOKEN_KEY));
+
+const saveTokens = (tokens) => {
+  localStorage.setItem(ACCESS_TOKEN_KEY, tokens.access_token);
+  localStorage.setItem(REFRESH_TOKEN_KEY, tokens.refresh_token);
+};
+
+export const logout = () => {
+  localStorage.removeItem(ACCESS_TOKEN_KEY);
+  localStorage.removeItem(REFRESH_TOKEN_KEY);
+};
+
+// error of a failed response, with the server's message when it sent one ({"error": "..."})
+export const responseError = async (response) => {
+  const data = await response.json().catch(() => ({}));
+  return new Error(data.error || `HTTP error! status: ${response.status}`);
+};
+
+const postJSON = async (path, body) => {
+  const response = await fetch(`${SERVER_URL}${path}`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify(body)
+  });
+  if (!response.ok) {
+    throw await responseError(response);
+  }
+  return response.json();
+};
+
+// valid job statuses and the moves allowed between them, see GET /statuses
+export const fetchStatuses = async () => {
+  const response = await fetch(`${SERVER_URL}/statuses`);
+  if (!response.ok) {
+    throw await responseError(response);
+  }
+  return response.json();
+};
+
+export const login = async (email, password) => {
+  saveTokens(await postJSON('/auth/login', { email, password }));
+};
+
+export const register = async (email, password) => {
+  await postJSON('/auth/register', { email, password });
+  await login(email, password);
+};
+
+// get a new token pair, returns false if the refresh token is no longer valid
+const refreshTokens = async () => {
+  const refreshToken = localStorage.getItem(REFRESH_TOKEN_KEY);
+  if (!refreshToken) return false;
+  try {
+    saveTokens(await postJSON('/auth/refresh', { refresh_token: refreshToken }));
+    return true;
+  } catch {
+    logout();
+    return false;
+  }
+};
+
+// fetch with the access token, refreshing it once when it has expired
+export const authFetch = async (url, options = {}) => {
+  const send = () => fetch(url, {
+    ...options,
+    headers: {
+      ...options.headers,
+      Authorization: `Bearer ${localStorage.getItem(ACCESS_TOKEN_KEY)}`
+    }
+  });
+
+  let response = await send();
+  if (response.status === 401 && await refreshTokens()) {
+    response = await send();
+  }
+  return response;
+};
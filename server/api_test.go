@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jobTracker/config"
+	"github.com/jobTracker/migrations"
+	"github.com/jobTracker/models"
+	"github.com/jobTracker/store"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard // request logs
+	os.Exit(m.Run())
+}
+
+// testServer is the API over a fresh in-memory SQLite database, which holds the users and tokens,
+// and the jobs and reviews of one store backend
+type testServer struct {
+	t       *testing.T
+	handler http.Handler
+}
+
+// runs test against the in-memory stores and the GORM stores, they must behave the same
+func forEachBackend(t *testing.T, test func(t *testing.T, srv *testServer)) {
+	for _, backend := range []string{"memory", "gorm"} {
+		t.Run(backend, func(t *testing.T) {
+			test(t, newTestServer(t, backend))
+		})
+	}
+}
+
+func newTestServer(t *testing.T, backend string) *testServer {
+	t.Helper()
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("DB_PATH", ":memory:")
+	t.Setenv("JWT_SECRET", strings.Repeat("test", 8))
+
+	previous := config.DB
+	config.Connect()
+	config.LoadAuth()
+	db := config.DB
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		config.DB = previous
+	})
+	if err := migrations.Up(db); err != nil {
+		t.Fatal(err)
+	}
+
+	var jobs store.JobStore = store.NewGormJobStore(db)
+	var reviews store.ReviewStore = store.NewGormReviewStore(db)
+	if backend == "memory" {
+		jobs, reviews = store.NewMemoryJobStore(), store.NewMemoryReviewStore()
+	}
+	return &testServer{t: t, handler: newRouter(jobs, reviews)}
+}
+
+// do sends body as JSON with the bearer token, if any
+func (s *testServer) do(token, method, path string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var payload io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		payload = bytes.NewReader(data)
+	}
+
+	req := httptest.NewRequest(method, path, payload)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.handler.ServeHTTP(w, req)
+	return w
+}
+
+// expect sends the request, fails the test unless it gets status and decodes the response into out (if not nil)
+func (s *testServer) expect(status int, token, method, path string, body, out any) {
+	s.t.Helper()
+	w := s.do(token, method, path, body)
+	if w.Code != status {
+		s.t.Fatalf("%s %s = %d %s, want %d", method, path, w.Code, w.Body, status)
+	}
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			s.t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+}
+
+// register and log in, returns the access token
+func (s *testServer) signUp(email string) string {
+	s.t.Helper()
+	credentials := gin.H{"email": email, "password": "correct horse"}
+	s.expect(http.StatusCreated, "", http.MethodPost, "/auth/register", credentials, nil)
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+	}
+	s.expect(http.StatusOK, "", http.MethodPost, "/auth/login", credentials, &tokens)
+	return tokens.AccessToken
+}
+
+func (s *testServer) createJob(token string, job gin.H) models.Job {
+	s.t.Helper()
+	var created models.Job
+	s.expect(http.StatusCreated, token, http.MethodPost, "/jobs", job, &created)
+	return created
+}
+
+// API token of the logged in user with these scopes
+func (s *testServer) createAPIToken(session string, scopes ...string) string {
+	s.t.Helper()
+	var created struct {
+		Token string `json:"token"`
+	}
+	s.expect(http.StatusCreated, session, http.MethodPost, "/tokens", gin.H{"name": "test", "scopes": scopes}, &created)
+	return created.Token
+}
+
+func jobIDs(jobs []models.Job) []uint {
+	ids := []uint{}
+	for _, job := range jobs {
+		ids = append(ids, job.ID)
+	}
+	return ids
+}
+
+func TestJobStatusTransitions(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, srv *testServer) {
+		token := srv.signUp("alice@example.com")
+		job := srv.createJob(token, gin.H{"company": "Acme", "title": "Backend Developer", "applied_date": "2025-10-01"})
+		if job.Status != models.StatusApplied {
+			t.Errorf("new job status = %q, want applied", job.Status)
+		}
+		srv.expect(http.StatusUnprocessableEntity, token, http.MethodPost, "/jobs", gin.H{"company": "Acme", "title": "Designer", "status": "hired"}, nil)
+
+		path := fmt.Sprintf("/jobs/%d", job.ID)
+		steps := []struct {
+			path   string
+			status string
+			want   int
+		}{
+			{path, "interview", http.StatusOK},
+			{path, "applied", http.StatusUnprocessableEntity}, // backwards
+			{path, "hired", http.StatusUnprocessableEntity},   // not a status
+			{path + "?force=true", "applied", http.StatusOK},
+			{path, "offer", http.StatusOK},
+		}
+		for _, step := range steps {
+			if w := srv.do(token, http.MethodPut, step.path, gin.H{"status": step.status}); w.Code != step.want {
+				t.Errorf("PUT %s to %s = %d %s, want %d", step.path, step.status, w.Code, w.Body, step.want)
+			}
+		}
+
+		var updated models.Job
+		srv.expect(http.StatusOK, token, http.MethodPut, path, gin.H{"notes": "Offer call on Monday"}, &updated)
+		if updated.Status != models.StatusOffer || updated.Company != "Acme" {
+			t.Errorf("a notes update changed the job: %+v", updated)
+		}
+
+		var history []models.JobStatusChange
+		srv.expect(http.StatusOK, token, http.MethodGet, path+"/history", nil, &history)
+		var moves []string
+		for _, change := range history {
+			moves = append(moves, fmt.Sprintf("%s->%s", change.FromStatus, change.ToStatus))
+		}
+		if want := []string{"->applied", "applied->interview", "interview->applied", "applied->offer"}; !slices.Equal(moves, want) {
+			t.Errorf("history = %v, want %v", moves, want)
+		}
+	})
+}
+
+func TestCreateJobRejectsDuplicates(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, srv *testServer) {
+		alice := srv.signUp("alice@example.com")
+		first := srv.createJob(alice, gin.H{"company": "Acme Inc.", "title": "Backend Developer", "applied_date": "2025-10-01", "source_message_id": "<1@acme.example>"})
+
+		duplicates := []struct {
+			name string
+			job  gin.H
+		}{
+			{"same email", gin.H{"company": "Someone Else", "title": "Analyst", "source_message_id": "<1@acme.example>"}},
+			{"same application", gin.H{"company": "ACME", "title": "backend developer", "applied_date": "2025-10-01"}},
+		}
+		for _, tt := range duplicates {
+			var conflict struct {
+				ID uint `json:"id"`
+			}
+			srv.expect(http.StatusConflict, alice, http.MethodPost, "/jobs", tt.job, &conflict)
+			if conflict.ID != first.ID {
+				t.Errorf("%s: 409 points at job %d, want %d", tt.name, conflict.ID, first.ID)
+			}
+		}
+		// another date is another application
+		srv.createJob(alice, gin.H{"company": "Acme", "title": "Backend Developer", "applied_date": "2025-11-01"})
+
+		var merged models.Job
+		srv.expect(http.StatusOK, alice, http.MethodPost, "/jobs?upsert=true",
+			gin.H{"company": "Acme", "title": "Backend Developer", "applied_date": "2025-10-01", "status": "interview", "location": "Remote", "notes": "Phone screen"}, &merged)
+		if merged.ID != first.ID || merged.Status != models.StatusInterview || merged.Location != "Remote" || merged.Notes != "Phone screen" {
+			t.Errorf("upsert = %+v", merged)
+		}
+
+		// jobs of other users are never duplicates
+		bob := srv.signUp("bob@example.com")
+		srv.createJob(bob, gin.H{"company": "Acme Inc.", "title": "Backend Developer", "applied_date": "2025-10-01", "source_message_id": "<1@acme.example>"})
+
+		var jobs []models.Job
+		srv.expect(http.StatusOK, alice, http.MethodGet, "/jobs", nil, &jobs)
+		if len(jobs) != 2 {
+			t.Errorf("alice has %d jobs, want 2", len(jobs))
+		}
+	})
+}
+
+func TestJobsAreScopedToTheirOwner(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, srv *testServer) {
+		alice, bob := srv.signUp("alice@example.com"), srv.signUp("bob@example.com")
+		job := srv.createJob(alice, gin.H{"company": "Acme", "title": "Backend Developer", "applied_date": "2025-10-01"})
+		var pending models.PendingJob
+		srv.expect(http.StatusCreated, alice, http.MethodPost, "/reviews", gin.H{"company": "Globex", "title": "Analyst", "email": gin.H{"subject": "Your application"}}, &pending)
+
+		path := fmt.Sprintf("/jobs/%d", job.ID)
+		forbidden := []struct {
+			method, path string
+			body         any
+		}{
+			{http.MethodPut, path, gin.H{"status": "rejected"}},
+			{http.MethodDelete, path, nil},
+			{http.MethodGet, path + "/history", nil},
+			{http.MethodPost, fmt.Sprintf("/reviews/%d/approve", pending.ID), nil},
+			{http.MethodPost, fmt.Sprintf("/reviews/%d/reject", pending.ID), nil},
+		}
+		for _, req := range forbidden {
+			if w := srv.do(bob, req.method, req.path, req.body); w.Code != http.StatusNotFound {
+				t.Errorf("bob %s %s = %d, want 404", req.method, req.path, w.Code)
+			}
+		}
+		for _, path := range []string{"/jobs", "/jobs/match?company=Acme", "/reviews"} {
+			var list []json.RawMessage
+			if srv.expect(http.StatusOK, bob, http.MethodGet, path, nil, &list); len(list) != 0 {
+				t.Errorf("bob GET %s = %s", path, list)
+			}
+		}
+
+		// API tokens act as their user, within their scopes
+		readOnly := srv.createAPIToken(alice, "jobs:read")
+		var jobs []models.Job
+		srv.expect(http.StatusOK, readOnly, http.MethodGet, "/jobs", nil, &jobs)
+		if len(jobs) != 1 || jobs[0].Status != models.StatusApplied {
+			t.Errorf("alice's jobs = %+v", jobs)
+		}
+		srv.expect(http.StatusForbidden, readOnly, http.MethodPost, "/jobs", gin.H{"company": "Initech", "title": "Dev"}, nil)
+		srv.expect(http.StatusForbidden, readOnly, http.MethodGet, "/tokens", nil, nil)
+		srv.expect(http.StatusUnauthorized, "", http.MethodGet, "/jobs", nil, nil)
+	})
+}
+
+func TestApproveAndRejectReviews(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, srv *testServer) {
+		alice := srv.signUp("alice@example.com")
+		parsed := gin.H{
+			"title":             "Analyst",
+			"applied_date":      "2025-10-01",
+			"source_message_id": "<7@globex.example>",
+			"confidence":        gin.H{"company": 0, "title": 0.6, "status": 0.8},
+			"email":             gin.H{"subject": "Your application", "from": "jobs@globex.example"},
+		}
+		srv.expect(http.StatusBadRequest, alice, http.MethodPost, "/reviews", gin.H{"title": "Analyst", "applied_date": "10/01/2025"}, nil)
+		var pending models.PendingJob
+		srv.expect(http.StatusCreated, alice, http.MethodPost, "/reviews", parsed, &pending)
+		var conflict struct {
+			ID uint `json:"id"`
+		}
+		if srv.expect(http.StatusConflict, alice, http.MethodPost, "/reviews", parsed, &conflict); conflict.ID != pending.ID {
+			t.Errorf("409 for a queued email has id %d, want %d", conflict.ID, pending.ID)
+		}
+
+		// the reviewer fills in the company the parser missed and corrects the status
+		approve := fmt.Sprintf("/reviews/%d/approve", pending.ID)
+		srv.expect(http.StatusUnprocessableEntity, alice, http.MethodPost, approve, nil, nil)
+		srv.expect(http.StatusBadRequest, alice, http.MethodPost, approve, gin.H{"company": "Globex", "applied_date": "Oct 1"}, nil)
+		var job models.Job
+		srv.expect(http.StatusCreated, alice, http.MethodPost, approve, gin.H{"company": "Globex", "status": "interview"}, &job)
+		if job.Company != "Globex" || job.Title != "Analyst" || job.Status != models.StatusInterview ||
+			job.AppliedDate != "2025-10-01" || job.SourceMessageID != "<7@globex.example>" {
+			t.Errorf("approved job = %+v", job)
+		}
+		var jobs []models.Job
+		if srv.expect(http.StatusOK, alice, http.MethodGet, "/jobs", nil, &jobs); len(jobs) != 1 || jobs[0].ID != job.ID {
+			t.Errorf("jobs = %+v", jobs)
+		}
+		srv.expect(http.StatusNotFound, alice, http.MethodPost, approve, nil, nil)
+
+		// the email is imported now
+		if srv.expect(http.StatusConflict, alice, http.MethodPost, "/reviews", parsed, &conflict); conflict.ID != job.ID {
+			t.Errorf("409 for an imported email has id %d, want job %d", conflict.ID, job.ID)
+		}
+
+		srv.expect(http.StatusCreated, alice, http.MethodPost, "/reviews", gin.H{"company": "Initech", "title": "Newsletter"}, &pending)
+		reject := fmt.Sprintf("/reviews/%d/reject", pending.ID)
+		srv.expect(http.StatusNoContent, alice, http.MethodPost, reject, nil, nil)
+		srv.expect(http.StatusNotFound, alice, http.MethodPost, reject, nil, nil)
+		var queue []models.PendingJob
+		if srv.expect(http.StatusOK, alice, http.MethodGet, "/reviews", nil, &queue); len(queue) != 0 {
+			t.Errorf("queue = %+v", queue)
+		}
+		if srv.expect(http.StatusOK, alice, http.MethodGet, "/jobs", nil, &jobs); len(jobs) != 1 {
+			t.Errorf("rejecting created a job: %+v", jobs)
+		}
+	})
+}
+
+func TestListJobsFiltersSortsAndPages(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, srv *testServer) {
+		token := srv.signUp("alice@example.com")
+		for _, job := range []gin.H{
+			{"company": "Acme", "title": "Backend Developer", "status": "applied", "applied_date": "2025-01-10"},
+			{"company": "Globex", "title": "Analyst", "status": "interview", "applied_date": "2025-02-01"},
+			{"company": "Acme Labs", "title": "Data Engineer", "status": "rejected", "applied_date": "2025-03-05"},
+			{"company": "Initech", "title": "Developer", "status": "offer", "applied_date": "2025-01-20"},
+		} {
+			srv.createJob(token, job)
+		}
+
+		tests := []struct {
+			query string
+			want  []uint
+			total string
+		}{
+			{"", []uint{1, 2, 3, 4}, "4"},
+			{"?status=applied,rejected", []uint{1, 3}, "2"},
+			{"?company=ACME", []uint{1, 3}, "2"},
+			{"?applied_from=2025-01-15&applied_to=2025-02-28", []uint{2, 4}, "2"},
+			{"?sort=applied_date&order=desc", []uint{3, 2, 4, 1}, "4"},
+			{"?sort=company", []uint{1, 3, 2, 4}, "4"},
+			{"?sort=status&order=desc&limit=2", []uint{3, 4}, "4"},
+			{"?page=2&limit=3", []uint{4}, "4"},
+		}
+		for _, tt := range tests {
+			w := srv.do(token, http.MethodGet, "/jobs"+tt.query, nil)
+			var jobs []models.Job
+			if err := json.Unmarshal(w.Body.Bytes(), &jobs); w.Code != http.StatusOK || err != nil {
+				t.Fatalf("GET /jobs%s = %d %s", tt.query, w.Code, w.Body)
+			}
+			if got := jobIDs(jobs); !slices.Equal(got, tt.want) || w.Header().Get("X-Total-Count") != tt.total {
+				t.Errorf("GET /jobs%s = %v (total %s), want %v (total %s)", tt.query, got, w.Header().Get("X-Total-Count"), tt.want, tt.total)
+			}
+		}
+
+		w := srv.do(token, http.MethodGet, "/jobs?page=2&limit=3", nil)
+		if w.Header().Get("X-Page") != "2" || w.Header().Get("X-Total-Pages") != "2" {
+			t.Errorf("paging headers = %v", w.Header())
+		}
+
+		for _, query := range []string{"?status=hired", "?applied_from=2025-1-1", "?sort=notes", "?order=up", "?page=0", "?limit=x"} {
+			srv.expect(http.StatusBadRequest, token, http.MethodGet, "/jobs"+query, nil, nil)
+		}
+		srv.expect(http.StatusBadRequest, token, http.MethodPost, "/jobs", gin.H{"company": "Hooli", "title": "Dev", "applied_date": "10/01/2025"}, nil)
+		srv.expect(http.StatusBadRequest, token, http.MethodPut, "/jobs/1", gin.H{"applied_date": "Oct 1"}, nil)
+	})
+}
+
+func TestDeleteTokenOnlyRevokesOwnTokens(t *testing.T) {
+	srv := newTestServer(t, "gorm")
+	alice, bob := srv.signUp("alice@example.com"), srv.signUp("bob@example.com")
+	watcher := srv.createAPIToken(alice, "jobs:write")
+	var tokens []models.APIToken
+	srv.expect(http.StatusOK, alice, http.MethodGet, "/tokens", nil, &tokens)
+	path := fmt.Sprintf("/tokens/%d", tokens[0].ID)
+
+	srv.expect(http.StatusNotFound, bob, http.MethodDelete, path, nil, nil)
+	for _, id := range []string{"0)%20OR%20(1=1", "1%20OR%201=1", "x", "-1"} {
+		srv.expect(http.StatusBadRequest, bob, http.MethodDelete, "/tokens/"+id, nil, nil)
+	}
+	srv.expect(http.StatusCreated, watcher, http.MethodPost, "/jobs", gin.H{"company": "Acme", "title": "Backend Developer"}, nil)
+
+	srv.expect(http.StatusNoContent, alice, http.MethodDelete, path, nil, nil)
+	srv.expect(http.StatusUnauthorized, watcher, http.MethodPost, "/jobs", gin.H{"company": "Globex", "title": "Analyst"}, nil)
+	srv.expect(http.StatusNotFound, alice, http.MethodDelete, path, nil, nil)
+}
+
+func TestDefaultAPITokenCanMatchJobs(t *testing.T) {
+	srv := newTestServer(t, "gorm")
+	alice := srv.signUp("alice@example.com")
+	watcher := srv.createAPIToken(alice)
+	srv.createJob(alice, gin.H{"company": "Acme", "title": "Backend Developer"})
+
+	// follow-up emails look up their job before updating it
+	var matches []json.RawMessage
+	if srv.expect(http.StatusOK, watcher, http.MethodGet, "/jobs/match?company=Acme", nil, &matches); len(matches) != 1 {
+		t.Errorf("matches = %s", matches)
+	}
+	srv.expect(http.StatusOK, watcher, http.MethodPut, "/jobs/1", gin.H{"status": "interview"}, nil)
+}
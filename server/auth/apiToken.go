@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// scopes that can be granted to an API token or session
+const (
+	ScopeJobsRead     = "jobs:read"
+	ScopeJobsWrite    = "jobs:write"
+	ScopeTokensManage = "tokens:manage" // only logged in users (never API tokens) can manage tokens
+)
+
+// APITokenScopes are the scopes an API token can be created with
+var APITokenScopes = []string{ScopeJobsRead, ScopeJobsWrite}
+
+// SessionScopes are granted to users logged in with a JWT access token
+var SessionScopes = []string{ScopeJobsRead, ScopeJobsWrite, ScopeTokensManage}
+
+// API tokens start with this so they are easy to tell apart from JWTs (and to find in leaked files)
+const APITokenPrefix = "jt_"
+
+// GenerateAPIToken returns a new random token and the hash to store for it
+func GenerateAPIToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = APITokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, HashAPIToken(token), nil
+}
+
+// HashAPIToken returns the hex sha256 of a token.
+// Tokens are random so a fast hash is enough, unlike passwords
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIToken reports whether a bearer token looks like an API token rather than a JWT
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// ValidAPITokenScope reports whether scope can be granted to an API token
+func ValidAPITokenScope(scope string) bool {
+	for _, s := range APITokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
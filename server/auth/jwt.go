@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jobTracker/config"
+)
+
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 7 * 24 * time.Hour
+
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+type Claims struct {
+	Type string `json:"typ"` // access or refresh
+	jwt.RegisteredClaims
+}
+
+// TokenPair is returned by login and refresh
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // access token lifetime in seconds
+}
+
+// IssueTokens creates a new access and refresh token for a user
+func IssueTokens(userID uint) (TokenPair, error) {
+	access, err := signToken(userID, accessTokenType, AccessTokenTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := signToken(userID, refreshTokenType, RefreshTokenTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// ParseAccessToken returns the user ID of a valid access token
+func ParseAccessToken(token string) (uint, error) {
+	return parseToken(token, accessTokenType)
+}
+
+// ParseRefreshToken returns the user ID of a valid refresh token
+func ParseRefreshToken(token string) (uint, error) {
+	return parseToken(token, refreshTokenType)
+}
+
+func signToken(userID uint, tokenType string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		Type: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(config.JWTSecret)
+}
+
+func parseToken(token, tokenType string) (uint, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		return config.JWTSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || claims.Type != tokenType {
+		return 0, ErrInvalidToken
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil || userID == 0 {
+		return 0, ErrInvalidToken
+	}
+	return uint(userID), nil
+}
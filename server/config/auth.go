@@ -0,0 +1,31 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+var JWTSecret []byte
+
+// LoadAuth reads the JWT signing secret, call after Connect so .env is loaded
+func LoadAuth() {
+	secret := os.Getenv("JWT_SECRET")
+	if len(secret) < 32 {
+		panic("JWT_SECRET must be set to at least 32 characters!")
+	}
+	JWTSecret = []byte(secret)
+}
+
+// AllowedOrigins returns the origins allowed by CORS (CORS_ORIGINS, comma separated)
+func AllowedOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(os.Getenv("CORS_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		origins = []string{"http://localhost:5173"} // vite dev server
+	}
+	return origins
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/glebarez/sqlite"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -11,19 +12,54 @@ import (
 
 var DB *gorm.DB
 
+// Connect opens the database selected by DB_DRIVER:
+//
+//	postgres (default)  uses DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME
+//	sqlite              uses DB_PATH, a file path or ":memory:" (default jobtracker.db)
 func Connect() {
 	godotenv.Load() // load env variable
 
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("DB_NAME"),
-		os.Getenv("DB_PORT"))
+	dialector, err := dialectorFromEnv()
+	if err != nil {
+		panic(err.Error())
+	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(dialector, &gorm.Config{})
 	if err != nil {
 		panic("Failed to connect to db!")
 	}
+
+	if dialector.Name() == "sqlite" {
+		// sqlite allows a single writer, and every connection to ":memory:" is a separate database
+		sqlDB, err := db.DB()
+		if err != nil {
+			panic("Failed to connect to db!")
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
 	DB = db
 }
+
+func dialectorFromEnv() (gorm.Dialector, error) {
+	switch driver := os.Getenv("DB_DRIVER"); driver {
+	case "", "postgres":
+		dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+			os.Getenv("DB_HOST"),
+			os.Getenv("DB_USER"),
+			os.Getenv("DB_PASSWORD"),
+			os.Getenv("DB_NAME"),
+			os.Getenv("DB_PORT"))
+		return postgres.Open(dsn), nil
+	case "sqlite":
+		path := os.Getenv("DB_PATH")
+		if path == "" {
+			path = "jobtracker.db"
+		}
+		if path == ":memory:" {
+			return sqlite.Open(path), nil
+		}
+		return sqlite.Open(path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"), nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q, use postgres or sqlite", driver)
+	}
+}
@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jobTracker/auth"
+	"github.com/jobTracker/config"
+	"github.com/jobTracker/models"
+	"gorm.io/gorm"
+)
+
+const minPasswordLength = 8
+
+type credentials struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+func Register(c *gin.Context) {
+	var req credentials
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if _, err := mail.ParseAddress(email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email address"})
+		return
+	}
+	if len(req.Password) < minPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters"})
+		return
+	}
+
+	var count int64
+	config.DB.Model(&models.User{}).Where("email = ?", email).Count(&count)
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := models.User{Email: email, PasswordHash: hash}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return claimUnownedJobs(tx, user.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+// the first user gets the jobs created before user accounts existed, when the database
+// was upgraded without any user (migration 8 assigns them when there already was one)
+func claimUnownedJobs(tx *gorm.DB, userID uint) error {
+	var users int64
+	if err := tx.Model(&models.User{}).Count(&users).Error; err != nil || users > 1 {
+		return err
+	}
+	result := tx.Model(&models.Job{}).Where("user_id IS NULL OR user_id = 0").Update("user_id", userID)
+	if result.RowsAffected > 0 {
+		log.Printf("Assigned %d jobs from before user accounts to user %d", result.RowsAffected, userID)
+	}
+	return result.Error
+}
+
+func Login(c *gin.Context) {
+	var req credentials
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if err := config.DB.Where("email = ?", email).First(&user).Error; err != nil || !auth.CheckPassword(user.PasswordHash, req.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
+
+	respondWithTokens(c, user.ID)
+}
+
+// Refresh swaps a valid refresh token for a new token pair
+func Refresh(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := auth.ParseRefreshToken(req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	// the user may have been deleted since the token was issued
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": auth.ErrInvalidToken.Error()})
+		return
+	}
+
+	respondWithTokens(c, user.ID)
+}
+
+func respondWithTokens(c *gin.Context, userID uint) {
+	tokens, err := auth.IssueTokens(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
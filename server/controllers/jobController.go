@@ -1,43 +1,250 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jobTracker/config"
+	"github.com/jobTracker/middleware"
 	"github.com/jobTracker/models"
+	"github.com/jobTracker/store"
 )
 
-func GetJobs(c *gin.Context) {
-	var jobs []models.Job
-	config.DB.Find(&jobs)
+// JobController serves /jobs from a JobStore
+type JobController struct {
+	jobs store.JobStore
+}
+
+func NewJobController(jobs store.JobStore) *JobController {
+	return &JobController{jobs: jobs}
+}
+
+// GetJobs lists jobs, optionally filtered, sorted and paginated (see parseJobQuery)
+func (jc *JobController) GetJobs(c *gin.Context) {
+	q, err := parseJobQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	jobs, total, err := jc.jobs.List(currentUserID(c), q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	setPaginationHeaders(c, q, total)
 	c.JSON(http.StatusOK, jobs)
 }
 
-func CreateJobs(c *gin.Context) {
+func (jc *JobController) CreateJobs(c *gin.Context) {
 	var job models.Job
 	if err := c.ShouldBindBodyWithJSON(&job); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	config.DB.Create(&job)
+	job.ID = 0
+	job.UserID = currentUserID(c)
+	if job.Status == "" {
+		job.Status = models.StatusApplied
+	}
+	// GET /jobs filters and sorts on applied_date as a YYYY-MM-DD string
+	if err := validateDateParam("applied_date", job.AppliedDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !job.Status.IsValid() {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": invalidStatusError(job.Status)})
+		return
+	}
+
+	existing, err := jc.jobs.FindDuplicate(job)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if existing != nil {
+		// ?upsert=true merges into the existing job instead of rejecting the duplicate
+		if upsert, _ := strconv.ParseBool(c.Query("upsert")); !upsert {
+			c.JSON(http.StatusConflict, gin.H{"error": "Job already exists", "id": existing.ID})
+			return
+		}
+		mergeJob(existing, job)
+		if err := jc.jobs.Update(existing); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, existing)
+		return
+	}
+
+	if err := jc.jobs.Create(&job); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusCreated, job)
 }
 
-func UpdateJobs(c *gin.Context) {
-	id := c.Param("id")
-	var job models.Job
-	if err := config.DB.First(&job, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+func (jc *JobController) UpdateJobs(c *gin.Context) {
+	job, ok := jc.findJob(c)
+	if !ok {
+		return
+	}
+	previousID, owner := job.ID, job.UserID
+	previousStatus := job.Status
+	if err := c.ShouldBindJSON(&job); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	job.ID, job.UserID = previousID, owner
+
+	if err := validateDateParam("applied_date", job.AppliedDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !job.Status.IsValid() {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": invalidStatusError(job.Status)})
+		return
+	}
+	// ?force=true allows moves the state machine would block (e.g. offer -> applied)
+	force, _ := strconv.ParseBool(c.Query("force"))
+	if !force && !previousStatus.CanTransitionTo(job.Status) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": fmt.Sprintf("cannot change status from %s to %s (use force=true to override)", previousStatus, job.Status),
+		})
+		return
+	}
+
+	if err := jc.jobs.Update(&job); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.BindJSON(&job)
-	config.DB.Save(&job)
 	c.JSON(http.StatusOK, job)
 }
 
-func DeleteJob(c *gin.Context) {
-	id := c.Param("id")
-	config.DB.Delete(&models.Job{}, id)
+func (jc *JobController) DeleteJob(c *gin.Context) {
+	id, ok := jobIDParam(c)
+	if !ok {
+		return
+	}
+	if err := jc.jobs.Delete(currentUserID(c), id); err != nil {
+		respondStoreError(c, err)
+		return
+	}
 	c.Status(http.StatusNoContent)
 }
+
+// MatchJobs finds the jobs a follow-up email about ?company= and ?title= (optional) refers to,
+// best match first, each with a score from 0 to 1
+func (jc *JobController) MatchJobs(c *gin.Context) {
+	company := strings.TrimSpace(c.Query("company"))
+	if company == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "company is required"})
+		return
+	}
+	limit := 5
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+		limit = n
+	}
+
+	matches, err := jc.jobs.Match(currentUserID(c), company, strings.TrimSpace(c.Query("title")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, matches[:min(limit, len(matches))])
+}
+
+// GetJobHistory lists status changes of a job, oldest first
+func (jc *JobController) GetJobHistory(c *gin.Context) {
+	id, ok := jobIDParam(c)
+	if !ok {
+		return
+	}
+	history, err := jc.jobs.History(currentUserID(c), id)
+	if err != nil {
+		respondStoreError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// load the current user's job from the :id param, responds with 404 when missing
+func (jc *JobController) findJob(c *gin.Context) (models.Job, bool) {
+	id, ok := jobIDParam(c)
+	if !ok {
+		return models.Job{}, false
+	}
+	job, err := jc.jobs.Get(currentUserID(c), id)
+	if err != nil {
+		respondStoreError(c, err)
+		return models.Job{}, false
+	}
+	return job, true
+}
+
+// parse the :id param, responds with 404 when it is not a valid ID
+func jobIDParam(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func respondStoreError(c *gin.Context, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// ID of the user authenticated by middleware.RequireAuth
+func currentUserID(c *gin.Context) uint {
+	return c.GetUint(middleware.UserIDKey)
+}
+
+// error message for a status outside models.JobStatuses
+func invalidStatusError(status models.JobStatus) string {
+	_, err := models.ParseJobStatus(string(status))
+	return err.Error()
+}
+
+// merge a duplicate into the existing job (used by POST /jobs?upsert=true):
+// fill empty fields, move status forward when allowed and append new notes
+func mergeJob(existing *models.Job, incoming models.Job) {
+	if existing.Company == "" {
+		existing.Company = incoming.Company
+	}
+	if existing.Title == "" {
+		existing.Title = incoming.Title
+	}
+	if existing.Location == "" {
+		existing.Location = incoming.Location
+	}
+	if existing.AppliedDate == "" {
+		existing.AppliedDate = incoming.AppliedDate
+	}
+	if existing.SourceMessageID == "" {
+		existing.SourceMessageID = incoming.SourceMessageID
+	}
+	if existing.Status.CanTransitionTo(incoming.Status) {
+		existing.Status = incoming.Status
+	}
+	if notes := strings.TrimSpace(incoming.Notes); notes != "" && !strings.Contains(existing.Notes, notes) {
+		if existing.Notes != "" {
+			existing.Notes += "\n"
+		}
+		existing.Notes += notes
+	}
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jobTracker/models"
+	"github.com/jobTracker/store"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// parse query string of GET /jobs
+//
+//	?status=applied,interview  ?company=acme
+//	?applied_from=2025-01-01&applied_to=2025-01-31
+//	?sort=applied_date&order=desc  ?page=2&limit=20
+func parseJobQuery(c *gin.Context) (store.JobQuery, error) {
+	q := store.JobQuery{
+		Company:     strings.TrimSpace(c.Query("company")),
+		AppliedFrom: strings.TrimSpace(c.Query("applied_from")),
+		AppliedTo:   strings.TrimSpace(c.Query("applied_to")),
+		Sort:        "id",
+		Order:       "asc",
+		Page:        1,
+	}
+
+	for _, s := range strings.Split(c.Query("status"), ",") {
+		if s = strings.TrimSpace(strings.ToLower(s)); s == "" {
+			continue
+		}
+		status, err := models.ParseJobStatus(s)
+		if err != nil {
+			return q, err
+		}
+		q.Statuses = append(q.Statuses, status)
+	}
+
+	if err := validateDateParam("applied_from", q.AppliedFrom); err != nil {
+		return q, err
+	}
+	if err := validateDateParam("applied_to", q.AppliedTo); err != nil {
+		return q, err
+	}
+
+	if sort := c.Query("sort"); sort != "" {
+		if !slices.Contains(store.SortFields, sort) {
+			return q, fmt.Errorf("cannot sort by %q", sort)
+		}
+		q.Sort = sort
+	}
+
+	if order := strings.ToLower(c.Query("order")); order != "" {
+		if order != "asc" && order != "desc" {
+			return q, fmt.Errorf("order must be asc or desc")
+		}
+		q.Order = order
+	}
+
+	// paginate only when the caller asks for it, so old clients still get every job
+	_, hasPage := c.GetQuery("page")
+	_, hasLimit := c.GetQuery("limit")
+	if hasPage || hasLimit {
+		q.Limit = defaultPageLimit
+	}
+	if hasPage {
+		page, err := strconv.Atoi(c.Query("page"))
+		if err != nil || page < 1 {
+			return q, fmt.Errorf("page must be a positive number")
+		}
+		q.Page = page
+	}
+	if hasLimit {
+		limit, err := strconv.Atoi(c.Query("limit"))
+		if err != nil || limit < 1 {
+			return q, fmt.Errorf("limit must be a positive number")
+		}
+		q.Limit = min(limit, maxPageLimit)
+	}
+
+	return q, nil
+}
+
+// empty is allowed, otherwise must be YYYY-MM-DD (query params and the applied_date of jobs)
+func validateDateParam(name, value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := time.Parse("2006-01-02", value); err != nil {
+		return fmt.Errorf("%s must be a date in YYYY-MM-DD format", name)
+	}
+	return nil
+}
+
+// write paging info to response headers so the body stays a plain array
+func setPaginationHeaders(c *gin.Context, q store.JobQuery, total int64) {
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	if q.Limit == 0 {
+		return
+	}
+	totalPages := (total + int64(q.Limit) - 1) / int64(q.Limit)
+	c.Header("X-Page", strconv.Itoa(q.Page))
+	c.Header("X-Per-Page", strconv.Itoa(q.Limit))
+	c.Header("X-Total-Pages", strconv.FormatInt(totalPages, 10))
+}
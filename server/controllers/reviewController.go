@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jobTracker/models"
+	"github.com/jobTracker/store"
+)
+
+// ReviewController serves /reviews, the queue of jobs parsed with low confidence
+type ReviewController struct {
+	reviews store.ReviewStore
+	jobs    store.JobStore
+}
+
+func NewReviewController(reviews store.ReviewStore, jobs store.JobStore) *ReviewController {
+	return &ReviewController{reviews: reviews, jobs: jobs}
+}
+
+// ListReviews lists the pending jobs, oldest first, with the email each comes from
+func (rc *ReviewController) ListReviews(c *gin.Context) {
+	pending, err := rc.reviews.List(currentUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pending)
+}
+
+// CreateReview queues a pending job (used by the email watcher).
+// An email already queued or already imported as a job is rejected with 409
+func (rc *ReviewController) CreateReview(c *gin.Context) {
+	var p models.PendingJob
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p.ID = 0
+	p.UserID = currentUserID(c)
+	if p.Status == "" {
+		p.Status = models.StatusApplied
+	}
+	// checked now rather than when the job is approved, like POST /jobs
+	if err := validateDateParam("applied_date", p.AppliedDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !p.Status.IsValid() {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": invalidStatusError(p.Status)})
+		return
+	}
+
+	if p.SourceMessageID != "" {
+		queued, err := rc.reviews.FindBySourceMessageID(p.UserID, p.SourceMessageID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if queued != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already waiting for review", "id": queued.ID})
+			return
+		}
+	}
+	existing, err := rc.jobs.FindDuplicate(p.Job())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Job already exists", "id": existing.ID})
+		return
+	}
+
+	if err := rc.reviews.Create(&p); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// ApproveReview turns a pending job into a job. The optional body holds corrections,
+// e.g. {"company": "Acme"}, and the job needs a company and a title once they are applied
+func (rc *ReviewController) ApproveReview(c *gin.Context) {
+	p, ok := rc.findPendingJob(c)
+	if !ok {
+		return
+	}
+
+	job := p.Job()
+	if err := c.ShouldBindJSON(&job); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	job.ID, job.UserID = 0, p.UserID
+
+	job.Company, job.Title = strings.TrimSpace(job.Company), strings.TrimSpace(job.Title)
+	if job.Company == "" || job.Title == "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "company and title are required, pass them to correct the parsed job"})
+		return
+	}
+	if err := validateDateParam("applied_date", job.AppliedDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !job.Status.IsValid() {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": invalidStatusError(job.Status)})
+		return
+	}
+
+	existing, err := rc.jobs.FindDuplicate(job)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Job already exists (reject the pending job instead)", "id": existing.ID})
+		return
+	}
+
+	if err := rc.jobs.Create(&job); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := rc.reviews.Delete(p.UserID, p.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, job)
+}
+
+// RejectReview drops a pending job
+func (rc *ReviewController) RejectReview(c *gin.Context) {
+	p, ok := rc.findPendingJob(c)
+	if !ok {
+		return
+	}
+	if err := rc.reviews.Delete(p.UserID, p.ID); err != nil {
+		respondReviewStoreError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// load the current user's pending job from the :id param, responds with 404 when missing
+func (rc *ReviewController) findPendingJob(c *gin.Context) (models.PendingJob, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pending job not found"})
+		return models.PendingJob{}, false
+	}
+	p, err := rc.reviews.Get(currentUserID(c), uint(id))
+	if err != nil {
+		respondReviewStoreError(c, err)
+		return models.PendingJob{}, false
+	}
+	return p, true
+}
+
+func respondReviewStoreError(c *gin.Context, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pending job not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jobTracker/models"
+)
+
+// GetStatuses returns the valid job statuses and the transitions allowed between them,
+// so the email watcher and the client use the same definition as the server
+func GetStatuses(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"statuses":    models.JobStatuses,
+		"transitions": models.JobStatusTransitions(),
+	})
+}
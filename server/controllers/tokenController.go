@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jobTracker/auth"
+	"github.com/jobTracker/config"
+	"github.com/jobTracker/models"
+)
+
+type createTokenRequest struct {
+	Name   string   `json:"name" binding:"required"`
+	Scopes []string `json:"scopes"` // defaults to jobs:read and jobs:write
+}
+
+// GetTokens lists the current user's API tokens (without the secret)
+func GetTokens(c *gin.Context) {
+	tokens := []models.APIToken{}
+	if err := config.DB.Where("user_id = ?", currentUserID(c)).Order("id").Find(&tokens).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateToken creates an API token, the plain token is only returned in this response
+func CreateToken(c *gin.Context) {
+	var req createTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.Scopes) == 0 {
+		// the email watcher reads GET /jobs/match before updating a job
+		req.Scopes = []string{auth.ScopeJobsRead, auth.ScopeJobsWrite}
+	}
+	for _, scope := range req.Scopes {
+		if !auth.ValidAPITokenScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope " + scope + ", must be one of " + strings.Join(auth.APITokenScopes, ", ")})
+			return
+		}
+	}
+
+	token, hash, err := auth.GenerateAPIToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	apiToken := models.APIToken{
+		UserID:    currentUserID(c),
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    token[:len(auth.APITokenPrefix)+6],
+		TokenHash: hash,
+		Scopes:    req.Scopes,
+	}
+	if err := config.DB.Create(&apiToken).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"token": token, "api_token": apiToken})
+}
+
+// DeleteToken revokes an API token
+func DeleteToken(c *gin.Context) {
+	// a string condition would be inlined as SQL, only ever pass the parsed number
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+	result := config.DB.Where("user_id = ?", currentUserID(c)).Delete(&models.APIToken{}, uint(id))
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
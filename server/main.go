@@ -1,23 +1,51 @@
 package main
 
 import (
+	"log"
+	"os"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/jobTracker/config"
-	"github.com/jobTracker/models"
+	"github.com/jobTracker/controllers"
+	"github.com/jobTracker/migrations"
 	"github.com/jobTracker/routes"
+	"github.com/jobTracker/store"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		exitOnError(runMigrate(os.Args[2:]))
+		return
+	}
+
 	config.Connect()
-	config.DB.AutoMigrate(&models.Job{})
+	config.LoadAuth()
 
-	r := gin.Default()
+	// apply pending migrations (see `migrate status`)
+	if err := migrations.Up(config.DB); err != nil {
+		log.Fatal("Failed to migrate db: ", err)
+	}
+
+	r := newRouter(store.NewGormJobStore(config.DB), store.NewGormReviewStore(config.DB))
+	r.Run(":8080")
+}
 
-	//enable cors
-	r.Use(cors.Default())
+// every route of the API, users and tokens are kept in config.DB
+func newRouter(jobs store.JobStore, reviews store.ReviewStore) *gin.Engine {
+	r := gin.Default()
 
-	routes.JobRoutes(r)
+	//enable cors (expose paging headers of GET /jobs to the browser)
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowOrigins = config.AllowedOrigins()
+	corsConfig.AddAllowHeaders("Authorization")
+	corsConfig.ExposeHeaders = []string{"X-Total-Count", "X-Page", "X-Per-Page", "X-Total-Pages"}
+	r.Use(cors.New(corsConfig))
 
-	r.Run(":8080")
+	routes.AuthRoutes(r)
+	routes.TokenRoutes(r)
+	routes.JobRoutes(r, controllers.NewJobController(jobs))
+	routes.ReviewRoutes(r, controllers.NewReviewController(reviews, jobs))
+	routes.StatusRoutes(r)
+	return r
 }
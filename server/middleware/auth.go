@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jobTracker/auth"
+	"github.com/jobTracker/config"
+	"github.com/jobTracker/models"
+)
+
+// context keys set by RequireAuth
+const (
+	UserIDKey = "userID" // ID of the authenticated user
+	ScopesKey = "scopes" // scopes of the session or API token
+)
+
+// RequireAuth rejects requests without a valid "Authorization: Bearer <token>" header.
+// The token is either a JWT access token from /auth/login or an API token from /tokens
+func RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing bearer token"})
+			return
+		}
+
+		if auth.IsAPIToken(token) {
+			apiToken, err := lookupAPIToken(token)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			c.Set(UserIDKey, apiToken.UserID)
+			c.Set(ScopesKey, apiToken.Scopes)
+			c.Next()
+			return
+		}
+
+		userID, err := auth.ParseAccessToken(token)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.Set(UserIDKey, userID)
+		c.Set(ScopesKey, auth.SessionScopes)
+		c.Next()
+	}
+}
+
+// RequireScope rejects requests whose session or API token lacks scope, use after RequireAuth
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(c.GetStringSlice(ScopesKey), scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Token is missing scope " + scope})
+			return
+		}
+		c.Next()
+	}
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// find the stored API token and record that it was used
+func lookupAPIToken(token string) (models.APIToken, error) {
+	var apiToken models.APIToken
+	if err := config.DB.Where("token_hash = ?", auth.HashAPIToken(token)).First(&apiToken).Error; err != nil {
+		return apiToken, auth.ErrInvalidToken
+	}
+
+	now := time.Now()
+	apiToken.LastUsedAt = &now
+	config.DB.Model(&apiToken).Update("last_used_at", now)
+	return apiToken, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/jobTracker/config"
+	"github.com/jobTracker/migrations"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up          apply every pending migration
+  down [n]    roll back the last n migrations (default 1)
+  status      list migrations and when they were applied`
+
+// run `server migrate up|down|status`
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+
+	config.Connect()
+
+	switch args[0] {
+	case "up":
+		if err := migrations.Up(config.DB); err != nil {
+			return err
+		}
+		fmt.Println("Database is up to date")
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("down expects a positive number of migrations, got %q", args[1])
+			}
+			steps = n
+		}
+		if err := migrations.Down(config.DB, steps); err != nil {
+			return err
+		}
+		fmt.Printf("Rolled back %d migration(s)\n", steps)
+	case "status":
+		statuses, err := migrations.Status(config.DB)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%03d_%-30s %s\n", s.Version, s.Name, applied)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], migrateUsage)
+	}
+	return nil
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
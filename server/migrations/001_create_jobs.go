@@ -0,0 +1,27 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// jobs table as it was first created by AutoMigrate.
+// Databases created before migrations existed already have it, so only create it when missing
+var createJobs = Migration{
+	Version: 1,
+	Name:    "create_jobs",
+	Up: func(tx *gorm.DB) error {
+		type job struct {
+			ID          uint `gorm:"primaryKey"`
+			Company     string
+			Title       string
+			Status      string
+			AppliedDate string
+			Notes       string
+		}
+		if tx.Migrator().HasTable(&job{}) {
+			return nil
+		}
+		return tx.Migrator().CreateTable(&job{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("jobs")
+	},
+}
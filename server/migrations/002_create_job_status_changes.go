@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var createJobStatusChanges = Migration{
+	Version: 2,
+	Name:    "create_job_status_changes",
+	Up: func(tx *gorm.DB) error {
+		type jobStatusChange struct {
+			ID         uint `gorm:"primaryKey"`
+			JobID      uint `gorm:"index"`
+			FromStatus string
+			ToStatus   string
+			ChangedAt  time.Time
+		}
+		if tx.Migrator().HasTable(&jobStatusChange{}) {
+			return nil
+		}
+		return tx.Migrator().CreateTable(&jobStatusChange{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("job_status_changes")
+	},
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type jobSourceMessageID struct {
+	SourceMessageID string `gorm:"index:idx_jobs_source_message_id"`
+}
+
+func (jobSourceMessageID) TableName() string { return "jobs" }
+
+var addJobSourceMessageID = Migration{
+	Version: 3,
+	Name:    "add_job_source_message_id",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if !m.HasColumn(&jobSourceMessageID{}, "SourceMessageID") {
+			if err := m.AddColumn(&jobSourceMessageID{}, "SourceMessageID"); err != nil {
+				return err
+			}
+		}
+		if m.HasIndex(&jobSourceMessageID{}, "idx_jobs_source_message_id") {
+			return nil
+		}
+		return m.CreateIndex(&jobSourceMessageID{}, "idx_jobs_source_message_id")
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if m.HasIndex(&jobSourceMessageID{}, "idx_jobs_source_message_id") {
+			if err := m.DropIndex(&jobSourceMessageID{}, "idx_jobs_source_message_id"); err != nil {
+				return err
+			}
+		}
+		return m.DropColumn(&jobSourceMessageID{}, "SourceMessageID")
+	},
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type user struct {
+	ID           uint   `gorm:"primaryKey"`
+	Email        string `gorm:"uniqueIndex"`
+	PasswordHash string
+	CreatedAt    time.Time
+}
+
+type jobOwner struct {
+	UserID uint `gorm:"index:idx_jobs_user_id"`
+}
+
+func (jobOwner) TableName() string { return "jobs" }
+
+// users table and the owner of each job
+var createUsers = Migration{
+	Version: 4,
+	Name:    "create_users",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if !m.HasTable(&user{}) {
+			if err := m.CreateTable(&user{}); err != nil {
+				return err
+			}
+		}
+		if !m.HasColumn(&jobOwner{}, "UserID") {
+			if err := m.AddColumn(&jobOwner{}, "UserID"); err != nil {
+				return err
+			}
+		}
+		if m.HasIndex(&jobOwner{}, "idx_jobs_user_id") {
+			return nil
+		}
+		return m.CreateIndex(&jobOwner{}, "idx_jobs_user_id")
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if m.HasIndex(&jobOwner{}, "idx_jobs_user_id") {
+			if err := m.DropIndex(&jobOwner{}, "idx_jobs_user_id"); err != nil {
+				return err
+			}
+		}
+		if err := m.DropColumn(&jobOwner{}, "UserID"); err != nil {
+			return err
+		}
+		return m.DropTable("users")
+	},
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var createAPITokens = Migration{
+	Version: 5,
+	Name:    "create_api_tokens",
+	Up: func(tx *gorm.DB) error {
+		type apiToken struct {
+			ID         uint `gorm:"primaryKey"`
+			UserID     uint `gorm:"index"`
+			Name       string
+			Prefix     string
+			TokenHash  string `gorm:"uniqueIndex"`
+			Scopes     string // JSON array
+			LastUsedAt *time.Time
+			CreatedAt  time.Time
+		}
+		if tx.Migrator().HasTable(&apiToken{}) {
+			return nil
+		}
+		return tx.Migrator().CreateTable(&apiToken{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("api_tokens")
+	},
+}
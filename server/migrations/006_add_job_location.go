@@ -0,0 +1,24 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type jobLocation struct {
+	Location string
+}
+
+func (jobLocation) TableName() string { return "jobs" }
+
+var addJobLocation = Migration{
+	Version: 6,
+	Name:    "add_job_location",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if m.HasColumn(&jobLocation{}, "Location") {
+			return nil
+		}
+		return m.AddColumn(&jobLocation{}, "Location")
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropColumn(&jobLocation{}, "Location")
+	},
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var createPendingJobs = Migration{
+	Version: 7,
+	Name:    "create_pending_jobs",
+	Up: func(tx *gorm.DB) error {
+		type pendingJob struct {
+			ID                uint `gorm:"primaryKey"`
+			UserID            uint `gorm:"index"`
+			Company           string
+			Title             string
+			Location          string
+			Status            string
+			AppliedDate       string
+			Notes             string
+			SourceMessageID   string `gorm:"index"`
+			ConfidenceCompany float64
+			ConfidenceTitle   float64
+			ConfidenceStatus  float64
+			EmailSubject      string
+			EmailFrom         string
+			EmailSnippet      string
+			CreatedAt         time.Time
+		}
+		if tx.Migrator().HasTable(&pendingJob{}) {
+			return nil
+		}
+		return tx.Migrator().CreateTable(&pendingJob{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("pending_jobs")
+	},
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// give the jobs created before user accounts (migration 4 left their user_id empty) to the first user,
+// otherwise nobody can see them. Without users yet, the first one claims them when registering
+var assignUnownedJobs = Migration{
+	Version: 8,
+	Name:    "assign_unowned_jobs",
+	Up: func(tx *gorm.DB) error {
+		var firstUserID uint
+		if err := tx.Table("users").Select("COALESCE(MIN(id), 0)").Scan(&firstUserID).Error; err != nil {
+			return err
+		}
+		if firstUserID == 0 {
+			return nil // no users yet, the first one claims them when registering
+		}
+		return tx.Table("jobs").Where("user_id IS NULL OR user_id = 0").Update("user_id", firstUserID).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		return nil // which jobs were unowned is not recorded, they keep their owner
+	},
+}
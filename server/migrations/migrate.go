@@ -0,0 +1,118 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one versioned schema change.
+// Up and Down run inside a transaction and must not use the structs in models,
+// because those follow the latest schema; declare the shape needed locally instead
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration is a row of schema_migrations, one per applied migration
+type SchemaMigration struct {
+	Version   uint `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// MigrationStatus is reported by `migrate status`
+type MigrationStatus struct {
+	Version   uint
+	Name      string
+	AppliedAt *time.Time // nil when pending
+}
+
+// Up applies every pending migration in version order
+func Up(db *gorm.DB) error {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range All {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d_%s up: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// Down rolls back the last `steps` applied migrations, newest first
+func Down(db *gorm.DB, steps int) error {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return err
+	}
+
+	for i := len(All) - 1; i >= 0 && steps > 0; i-- {
+		m := All[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+		}
+		steps--
+	}
+	return nil
+}
+
+// Status lists every known migration and when it was applied
+func Status(db *gorm.DB) ([]MigrationStatus, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(All))
+	for _, m := range All {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if row, ok := applied[m.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// create schema_migrations if needed and load applied versions
+func appliedVersions(db *gorm.DB) (map[uint]SchemaMigration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+
+	var rows []SchemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[uint]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
@@ -0,0 +1,14 @@
+package migrations
+
+// All lists every migration in the order they are applied.
+// Never edit or reorder a migration that has been released, add a new one instead
+var All = []Migration{
+	createJobs,
+	createJobStatusChanges,
+	addJobSourceMessageID,
+	createUsers,
+	createAPITokens,
+	addJobLocation,
+	createPendingJobs,
+	assignUnownedJobs,
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// APIToken is a long-lived token for scripts like the email watcher
+type APIToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"-" gorm:"index"`
+	Name       string     `json:"name"`                          // e.g. "laptop watcher"
+	Prefix     string     `json:"prefix"`                        // first characters of the token, to tell tokens apart
+	TokenHash  string     `json:"-" gorm:"uniqueIndex"`          // sha256 of the token, the token itself is never stored
+	Scopes     []string   `json:"scopes" gorm:"serializer:json"` // e.g. jobs:write
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
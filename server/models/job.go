@@ -1,10 +1,13 @@
 package models
 
 type Job struct {
-	ID          uint   `json:"id" gorm:"primaryKey"`
-	Company     string `json:"company"` //company name
-	Title       string `json:"title"`   //job title
-	Status      string `json:"status"`  // applied,interview,offer,rejected
-	AppliedDate string `json:"applied_date"`
-	Notes       string `json:"notes"`
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	UserID          uint      `json:"user_id" gorm:"index"` // owner of the job
+	Company         string    `json:"company"`              //company name
+	Title           string    `json:"title"`                //job title
+	Location        string    `json:"location"`             // e.g. "Toronto, ON (Hybrid)"
+	Status          JobStatus `json:"status"`               // applied,interview,offer,rejected
+	AppliedDate     string    `json:"applied_date"`
+	Notes           string    `json:"notes"`
+	SourceMessageID string    `json:"source_message_id,omitempty" gorm:"index"` // Message-ID of the imported email
 }
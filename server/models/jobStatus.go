@@ -0,0 +1,61 @@
+package models
+
+import "fmt"
+
+// JobStatus is the stage of a job application
+type JobStatus string
+
+const (
+	StatusApplied   JobStatus = "applied"
+	StatusInterview JobStatus = "interview"
+	StatusOffer     JobStatus = "offer"
+	StatusRejected  JobStatus = "rejected"
+)
+
+// JobStatuses lists every valid status in pipeline order
+var JobStatuses = []JobStatus{StatusApplied, StatusInterview, StatusOffer, StatusRejected}
+
+// allowed moves from one status to another (staying on the same status is always allowed)
+var jobStatusTransitions = map[JobStatus][]JobStatus{
+	StatusApplied:   {StatusInterview, StatusOffer, StatusRejected},
+	StatusInterview: {StatusOffer, StatusRejected},
+	StatusOffer:     {StatusRejected},
+	StatusRejected:  {},
+}
+
+// ParseJobStatus returns the status named s or an error if it is not a valid status
+func ParseJobStatus(s string) (JobStatus, error) {
+	status := JobStatus(s)
+	if !status.IsValid() {
+		return "", fmt.Errorf("invalid status %q, must be one of %v", s, JobStatuses)
+	}
+	return status, nil
+}
+
+// IsValid reports whether s is one of JobStatuses
+func (s JobStatus) IsValid() bool {
+	_, ok := jobStatusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether a job can move from s to next without forcing it
+func (s JobStatus) CanTransitionTo(next JobStatus) bool {
+	if s == next {
+		return true
+	}
+	for _, allowed := range jobStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// JobStatusTransitions returns a copy of the allowed transitions for every status
+func JobStatusTransitions() map[JobStatus][]JobStatus {
+	transitions := make(map[JobStatus][]JobStatus, len(jobStatusTransitions))
+	for status, next := range jobStatusTransitions {
+		transitions[status] = append([]JobStatus{}, next...)
+	}
+	return transitions
+}
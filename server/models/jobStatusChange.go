@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// JobStatusChange is one row of a job's status history
+type JobStatusChange struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	JobID      uint      `json:"job_id" gorm:"index"`
+	FromStatus JobStatus `json:"from_status"` // empty when the job was created
+	ToStatus   JobStatus `json:"to_status"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// PendingJob is a job parsed from an email with low confidence, it waits in the review queue
+// until the user approves it (possibly with edits) as a Job or rejects it
+type PendingJob struct {
+	ID              uint            `json:"id" gorm:"primaryKey"`
+	UserID          uint            `json:"user_id" gorm:"index"`
+	Company         string          `json:"company"` // empty when the parser found none
+	Title           string          `json:"title"`
+	Location        string          `json:"location"`
+	Status          JobStatus       `json:"status"`
+	AppliedDate     string          `json:"applied_date"`
+	Notes           string          `json:"notes"`
+	SourceMessageID string          `json:"source_message_id,omitempty" gorm:"index"`
+	Confidence      FieldConfidence `json:"confidence" gorm:"embedded;embeddedPrefix:confidence_"`
+	Email           EmailContext    `json:"email" gorm:"embedded;embeddedPrefix:email_"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// FieldConfidence is how sure the parser was of each field, from 0 (not found) to 1 (certain)
+type FieldConfidence struct {
+	Company float64 `json:"company"`
+	Title   float64 `json:"title"`
+	Status  float64 `json:"status"`
+}
+
+// EmailContext is the email a pending job comes from, shown while reviewing it
+type EmailContext struct {
+	Subject string `json:"subject"`
+	From    string `json:"from"`
+	Snippet string `json:"snippet"` // start of the body
+}
+
+// Job returns the job that approving p creates
+func (p PendingJob) Job() Job {
+	return Job{
+		UserID:          p.UserID,
+		Company:         p.Company,
+		Title:           p.Title,
+		Location:        p.Location,
+		Status:          p.Status,
+		AppliedDate:     p.AppliedDate,
+		Notes:           p.Notes,
+		SourceMessageID: p.SourceMessageID,
+	}
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+type User struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Email        string    `json:"email" gorm:"uniqueIndex"`
+	PasswordHash string    `json:"-"` // bcrypt hash, never returned by the API
+	CreatedAt    time.Time `json:"created_at"`
+}
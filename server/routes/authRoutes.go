@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jobTracker/controllers"
+)
+
+func AuthRoutes(r *gin.Engine) {
+	authGroup := r.Group("/auth")
+	{
+		authGroup.POST("/register", controllers.Register) // create account
+		authGroup.POST("/login", controllers.Login)       // get access + refresh token
+		authGroup.POST("/refresh", controllers.Refresh)   // new tokens from refresh token
+	}
+}
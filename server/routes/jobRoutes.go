@@ -2,15 +2,22 @@ package routes
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/jobTracker/auth"
 	"github.com/jobTracker/controllers"
+	"github.com/jobTracker/middleware"
 )
 
-func JobRoutes(r *gin.Engine) {
-	job := r.Group("/jobs")
+func JobRoutes(r *gin.Engine, jobs *controllers.JobController) {
+	read := middleware.RequireScope(auth.ScopeJobsRead)
+	write := middleware.RequireScope(auth.ScopeJobsWrite)
+
+	job := r.Group("/jobs", middleware.RequireAuth())
 	{
-		job.GET("", controllers.GetJobs)          // get all job
-		job.POST("", controllers.CreateJobs)      // create
-		job.PUT("/:id", controllers.UpdateJobs)   // update
-		job.DELETE("/:id", controllers.DeleteJob) // delete
+		job.GET("", read, jobs.GetJobs)                   // get all job
+		job.POST("", write, jobs.CreateJobs)              // create
+		job.GET("/match", read, jobs.MatchJobs)           // find jobs by company and title
+		job.PUT("/:id", write, jobs.UpdateJobs)           // update
+		job.DELETE("/:id", write, jobs.DeleteJob)         // delete
+		job.GET("/:id/history", read, jobs.GetJobHistory) // status history
 	}
 }
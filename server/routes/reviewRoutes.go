@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jobTracker/auth"
+	"github.com/jobTracker/controllers"
+	"github.com/jobTracker/middleware"
+)
+
+func ReviewRoutes(r *gin.Engine, reviews *controllers.ReviewController) {
+	read := middleware.RequireScope(auth.ScopeJobsRead)
+	write := middleware.RequireScope(auth.ScopeJobsWrite)
+
+	review := r.Group("/reviews", middleware.RequireAuth())
+	{
+		review.GET("", read, reviews.ListReviews)                 // pending jobs
+		review.POST("", write, reviews.CreateReview)              // queue a job parsed with low confidence
+		review.POST("/:id/approve", write, reviews.ApproveReview) // create the job, with optional edits
+		review.POST("/:id/reject", write, reviews.RejectReview)   // drop it
+	}
+}
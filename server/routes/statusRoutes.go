@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jobTracker/controllers"
+)
+
+func StatusRoutes(r *gin.Engine) {
+	r.GET("/statuses", controllers.GetStatuses) // valid statuses and transitions
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jobTracker/auth"
+	"github.com/jobTracker/controllers"
+	"github.com/jobTracker/middleware"
+)
+
+func TokenRoutes(r *gin.Engine) {
+	token := r.Group("/tokens", middleware.RequireAuth(), middleware.RequireScope(auth.ScopeTokensManage))
+	{
+		token.GET("", controllers.GetTokens)          // list API tokens
+		token.POST("", controllers.CreateToken)       // create
+		token.DELETE("/:id", controllers.DeleteToken) // revoke
+	}
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/jobTracker/models"
+)
+
+// legal suffixes ignored when comparing company names
+var companySuffixes = map[string]bool{
+	"inc": true, "llc": true, "ltd": true, "limited": true, "corp": true,
+	"corporation": true, "co": true, "company": true, "gmbh": true,
+}
+
+// lower case, drop punctuation and collapse spaces so "Acme, Inc." matches "acme"
+func normalizeJobField(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return strings.Join(words, " ")
+}
+
+func normalizeCompany(s string) string {
+	words := strings.Fields(normalizeJobField(s))
+	for len(words) > 1 && companySuffixes[words[len(words)-1]] {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// find the candidate that is the same application as job:
+// same company, title and applied date after normalizing
+func findSameApplication(candidates []models.Job, job models.Job) *models.Job {
+	company := normalizeCompany(job.Company)
+	title := normalizeJobField(job.Title)
+	for i := range candidates {
+		if candidates[i].AppliedDate != job.AppliedDate {
+			continue
+		}
+		if normalizeCompany(candidates[i].Company) == company && normalizeJobField(candidates[i].Title) == title {
+			return &candidates[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package store
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jobTracker/models"
+	"gorm.io/gorm"
+)
+
+// GormJobStore is the JobStore used by the server (Postgres or SQLite)
+type GormJobStore struct {
+	db *gorm.DB
+}
+
+func NewGormJobStore(db *gorm.DB) *GormJobStore {
+	return &GormJobStore{db: db}
+}
+
+// jobs owned by a user (safe to reuse for several queries)
+func (s *GormJobStore) userJobs(userID uint) *gorm.DB {
+	return s.db.Where("user_id = ?", userID).Session(&gorm.Session{})
+}
+
+func (s *GormJobStore) List(userID uint, q JobQuery) ([]models.Job, int64, error) {
+	var total int64
+	if err := filterJobs(s.userJobs(userID).Model(&models.Job{}), q).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	jobs := []models.Job{}
+	if err := pageJobs(filterJobs(s.userJobs(userID), q), q).Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+func (s *GormJobStore) Get(userID, id uint) (models.Job, error) {
+	var job models.Job
+	err := s.userJobs(userID).First(&job, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return job, ErrNotFound
+	}
+	return job, err
+}
+
+func (s *GormJobStore) Create(job *models.Job) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		return recordStatusChange(tx, job.ID, "", job.Status)
+	})
+}
+
+func (s *GormJobStore) Update(job *models.Job) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var current models.Job
+		if err := tx.Where("user_id = ?", job.UserID).First(&current, job.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if err := tx.Save(job).Error; err != nil {
+			return err
+		}
+		if job.Status == current.Status {
+			return nil
+		}
+		return recordStatusChange(tx, job.ID, current.Status, job.Status)
+	})
+}
+
+func (s *GormJobStore) Delete(userID, id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ?", userID).Delete(&models.Job{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return tx.Where("job_id = ?", id).Delete(&models.JobStatusChange{}).Error
+	})
+}
+
+func (s *GormJobStore) History(userID, id uint) ([]models.JobStatusChange, error) {
+	job, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	history := []models.JobStatusChange{}
+	err = s.db.Where("job_id = ?", job.ID).Order("changed_at, id").Find(&history).Error
+	return history, err
+}
+
+func (s *GormJobStore) FindDuplicate(job models.Job) (*models.Job, error) {
+	jobs := s.userJobs(job.UserID)
+	if job.SourceMessageID != "" {
+		var existing models.Job
+		if err := jobs.Where("source_message_id = ?", job.SourceMessageID).Limit(1).Find(&existing).Error; err != nil {
+			return nil, err
+		}
+		if existing.ID != 0 {
+			return &existing, nil
+		}
+	}
+
+	var candidates []models.Job
+	if err := jobs.Where("applied_date = ?", job.AppliedDate).Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	return findSameApplication(candidates, job), nil
+}
+
+func (s *GormJobStore) Match(userID uint, company, title string) ([]JobMatch, error) {
+	// names are compared after normalizing, which SQL cannot do
+	var jobs []models.Job
+	if err := s.userJobs(userID).Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+	return rankMatches(jobs, company, title), nil
+}
+
+// apply filters (not sort or paging) to a job query
+func filterJobs(db *gorm.DB, q JobQuery) *gorm.DB {
+	if len(q.Statuses) > 0 {
+		db = db.Where("status IN ?", q.Statuses)
+	}
+	if q.Company != "" {
+		db = db.Where("LOWER(company) LIKE ?", "%"+strings.ToLower(q.Company)+"%")
+	}
+	// applied_date is stored as YYYY-MM-DD so string comparison keeps date order
+	if q.AppliedFrom != "" {
+		db = db.Where("applied_date >= ?", q.AppliedFrom)
+	}
+	if q.AppliedTo != "" {
+		db = db.Where("applied_date <= ?", q.AppliedTo)
+	}
+	return db
+}
+
+// apply sort and paging to a job query, q.Sort must be one of SortFields
+func pageJobs(db *gorm.DB, q JobQuery) *gorm.DB {
+	sort, order := q.Sort, q.Order
+	if !slices.Contains(SortFields, sort) {
+		sort = "id"
+	}
+	if order != "desc" {
+		order = "asc"
+	}
+
+	db = db.Order(sort + " " + order)
+	if sort != "id" {
+		db = db.Order("id " + order) // stable order between pages
+	}
+	if q.Limit > 0 {
+		db = db.Limit(q.Limit).Offset((max(q.Page, 1) - 1) * q.Limit)
+	}
+	return db
+}
+
+// add a row to the status history of a job
+func recordStatusChange(tx *gorm.DB, jobID uint, from, to models.JobStatus) error {
+	return tx.Create(&models.JobStatusChange{
+		JobID:      jobID,
+		FromStatus: from,
+		ToStatus:   to,
+		ChangedAt:  time.Now(),
+	}).Error
+}
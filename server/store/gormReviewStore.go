@@ -0,0 +1,62 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/jobTracker/models"
+	"gorm.io/gorm"
+)
+
+// GormReviewStore is the ReviewStore used by the server
+type GormReviewStore struct {
+	db *gorm.DB
+}
+
+func NewGormReviewStore(db *gorm.DB) *GormReviewStore {
+	return &GormReviewStore{db: db}
+}
+
+func (s *GormReviewStore) userPendingJobs(userID uint) *gorm.DB {
+	return s.db.Where("user_id = ?", userID).Session(&gorm.Session{})
+}
+
+func (s *GormReviewStore) List(userID uint) ([]models.PendingJob, error) {
+	pending := []models.PendingJob{}
+	err := s.userPendingJobs(userID).Order("created_at, id").Find(&pending).Error
+	return pending, err
+}
+
+func (s *GormReviewStore) Get(userID, id uint) (models.PendingJob, error) {
+	var p models.PendingJob
+	err := s.userPendingJobs(userID).First(&p, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return p, ErrNotFound
+	}
+	return p, err
+}
+
+func (s *GormReviewStore) Create(p *models.PendingJob) error {
+	return s.db.Create(p).Error
+}
+
+func (s *GormReviewStore) FindBySourceMessageID(userID uint, messageID string) (*models.PendingJob, error) {
+	var p models.PendingJob
+	if err := s.userPendingJobs(userID).Where("source_message_id = ?", messageID).Limit(1).Find(&p).Error; err != nil {
+		return nil, err
+	}
+	if p.ID == 0 {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func (s *GormReviewStore) Delete(userID, id uint) error {
+	result := s.userPendingJobs(userID).Delete(&models.PendingJob{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/jobTracker/models"
+)
+
+// JobMatch is a job with how well it matches a company and title, from 0 to 1
+type JobMatch struct {
+	models.Job
+	Score float64 `json:"score"`
+}
+
+// rank the jobs an email about company and title (optional) may refer to, best first.
+// The company must be the same after normalizing, or one name must contain the other
+// ("Wayne" and "Wayne Enterprises"). Ties go to the most recent application
+func rankMatches(jobs []models.Job, company, title string) []JobMatch {
+	company = normalizeCompany(company)
+	titleWords := strings.Fields(normalizeJobField(title))
+
+	matches := []JobMatch{}
+	for _, job := range jobs {
+		score := companySimilarity(company, normalizeCompany(job.Company))
+		if score == 0 {
+			continue
+		}
+		if len(titleWords) > 0 {
+			score *= 0.5 + 0.5*wordSimilarity(titleWords, strings.Fields(normalizeJobField(job.Title)))
+		}
+		matches = append(matches, JobMatch{Job: job, Score: score})
+	}
+
+	slices.SortStableFunc(matches, func(a, b JobMatch) int {
+		return cmp.Or(
+			cmp.Compare(b.Score, a.Score),
+			cmp.Compare(b.AppliedDate, a.AppliedDate),
+			cmp.Compare(b.ID, a.ID),
+		)
+	})
+	return matches
+}
+
+// 1 for the same normalized name, 0.8 when one contains the other's words, else 0
+func companySimilarity(a, b string) float64 {
+	switch {
+	case a == "" || b == "":
+		return 0
+	case a == b:
+		return 1
+	case strings.Contains(" "+a+" ", " "+b+" ") || strings.Contains(" "+b+" ", " "+a+" "):
+		return 0.8
+	}
+	return 0
+}
+
+// share of distinct words the two titles have in common (Jaccard index)
+func wordSimilarity(a, b []string) float64 {
+	setA, setB := wordSet(a), wordSet(b)
+	common := 0
+	for w := range setA {
+		if setB[w] {
+			common++
+		}
+	}
+	union := len(setA) + len(setB) - common
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+func wordSet(words []string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/jobTracker/models"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// SortFields are the job fields that can be used to sort a JobQuery
+var SortFields = []string{"id", "company", "title", "status", "applied_date"}
+
+// JobQuery holds the filters, sort and paging options of a job listing
+type JobQuery struct {
+	Statuses    []models.JobStatus
+	Company     string // case-insensitive substring
+	AppliedFrom string // YYYY-MM-DD, inclusive
+	AppliedTo   string // YYYY-MM-DD, inclusive
+	Sort        string // one of SortFields, default id
+	Order       string // asc or desc, default asc
+	Page        int    // starts at 1
+	Limit       int    // 0 means no pagination (return every match)
+}
+
+// JobStore persists jobs and their status history.
+// Every read is scoped to the owning user, Create and Update use job.UserID
+type JobStore interface {
+	// List returns one page of the user's jobs matching q and the total number of matches
+	List(userID uint, q JobQuery) ([]models.Job, int64, error)
+	// Get returns ErrNotFound if the job does not exist or belongs to another user
+	Get(userID, id uint) (models.Job, error)
+	// Create saves a new job and records its first status
+	Create(job *models.Job) error
+	// Update saves an existing job and records a status change if the status differs from the stored one
+	Update(job *models.Job) error
+	// Delete removes a job and its history, ErrNotFound if the user has no such job
+	Delete(userID, id uint) error
+	// History returns the status changes of a job, oldest first
+	History(userID, id uint) ([]models.JobStatusChange, error)
+	// FindDuplicate returns the job.UserID's job that is the same application as job, or nil
+	FindDuplicate(job models.Job) (*models.Job, error)
+	// Match returns the user's jobs that may be the application to company and title (optional), best first
+	Match(userID uint, company, title string) ([]JobMatch, error)
+}
@@ -0,0 +1,198 @@
+package store
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jobTracker/models"
+)
+
+// MemoryJobStore keeps jobs in memory, for tests and running without a database
+type MemoryJobStore struct {
+	mu      sync.Mutex
+	jobs    map[uint]models.Job
+	history []models.JobStatusChange
+	nextID  uint
+}
+
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{jobs: map[uint]models.Job{}, nextID: 1}
+}
+
+func (s *MemoryJobStore) List(userID uint, q JobQuery) ([]models.Job, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := []models.Job{}
+	for _, job := range s.jobs {
+		if job.UserID == userID && matchesJobQuery(job, q) {
+			jobs = append(jobs, job)
+		}
+	}
+	sortJobs(jobs, q)
+
+	total := int64(len(jobs))
+	if q.Limit > 0 {
+		start := min((max(q.Page, 1)-1)*q.Limit, len(jobs))
+		end := min(start+q.Limit, len(jobs))
+		jobs = jobs[start:end]
+	}
+	return jobs, total, nil
+}
+
+func (s *MemoryJobStore) Get(userID, id uint) (models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.UserID != userID {
+		return models.Job{}, ErrNotFound
+	}
+	return job, nil
+}
+
+func (s *MemoryJobStore) Create(job *models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job.ID = s.nextID
+	s.nextID++
+	s.jobs[job.ID] = *job
+	s.recordStatusChange(job.ID, "", job.Status)
+	return nil
+}
+
+func (s *MemoryJobStore) Update(job *models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.jobs[job.ID]
+	if !ok || current.UserID != job.UserID {
+		return ErrNotFound
+	}
+	s.jobs[job.ID] = *job
+	if job.Status != current.Status {
+		s.recordStatusChange(job.ID, current.Status, job.Status)
+	}
+	return nil
+}
+
+func (s *MemoryJobStore) Delete(userID, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.UserID != userID {
+		return ErrNotFound
+	}
+	delete(s.jobs, id)
+	s.history = slices.DeleteFunc(s.history, func(change models.JobStatusChange) bool {
+		return change.JobID == id
+	})
+	return nil
+}
+
+func (s *MemoryJobStore) History(userID, id uint) ([]models.JobStatusChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || job.UserID != userID {
+		return nil, ErrNotFound
+	}
+
+	history := []models.JobStatusChange{}
+	for _, change := range s.history {
+		if change.JobID == id {
+			history = append(history, change)
+		}
+	}
+	return history, nil
+}
+
+func (s *MemoryJobStore) FindDuplicate(job models.Job) (*models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var candidates []models.Job
+	for _, existing := range s.jobs {
+		if existing.UserID != job.UserID {
+			continue
+		}
+		if job.SourceMessageID != "" && existing.SourceMessageID == job.SourceMessageID {
+			return &existing, nil
+		}
+		candidates = append(candidates, existing)
+	}
+	slices.SortFunc(candidates, func(a, b models.Job) int { return cmp.Compare(a.ID, b.ID) })
+	return findSameApplication(candidates, job), nil
+}
+
+func (s *MemoryJobStore) Match(userID uint, company, title string) ([]JobMatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var jobs []models.Job
+	for _, job := range s.jobs {
+		if job.UserID == userID {
+			jobs = append(jobs, job)
+		}
+	}
+	return rankMatches(jobs, company, title), nil
+}
+
+// history is append only so it stays in changed_at order
+func (s *MemoryJobStore) recordStatusChange(jobID uint, from, to models.JobStatus) {
+	s.history = append(s.history, models.JobStatusChange{
+		ID:         uint(len(s.history) + 1),
+		JobID:      jobID,
+		FromStatus: from,
+		ToStatus:   to,
+		ChangedAt:  time.Now(),
+	})
+}
+
+// same filters as filterJobs in the gorm store
+func matchesJobQuery(job models.Job, q JobQuery) bool {
+	if len(q.Statuses) > 0 && !slices.Contains(q.Statuses, job.Status) {
+		return false
+	}
+	if q.Company != "" && !strings.Contains(strings.ToLower(job.Company), strings.ToLower(q.Company)) {
+		return false
+	}
+	if q.AppliedFrom != "" && job.AppliedDate < q.AppliedFrom {
+		return false
+	}
+	if q.AppliedTo != "" && job.AppliedDate > q.AppliedTo {
+		return false
+	}
+	return true
+}
+
+// same order as pageJobs in the gorm store
+func sortJobs(jobs []models.Job, q JobQuery) {
+	field := func(job models.Job) string {
+		switch q.Sort {
+		case "company":
+			return job.Company
+		case "title":
+			return job.Title
+		case "status":
+			return string(job.Status)
+		case "applied_date":
+			return job.AppliedDate
+		}
+		return ""
+	}
+
+	slices.SortFunc(jobs, func(a, b models.Job) int {
+		c := cmp.Or(cmp.Compare(field(a), field(b)), cmp.Compare(a.ID, b.ID))
+		if q.Order == "desc" {
+			return -c
+		}
+		return c
+	})
+}
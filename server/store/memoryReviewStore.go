@@ -0,0 +1,85 @@
+package store
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jobTracker/models"
+)
+
+// MemoryReviewStore keeps pending jobs in memory, for tests and running without a database
+type MemoryReviewStore struct {
+	mu      sync.Mutex
+	pending map[uint]models.PendingJob
+	nextID  uint
+}
+
+func NewMemoryReviewStore() *MemoryReviewStore {
+	return &MemoryReviewStore{pending: map[uint]models.PendingJob{}, nextID: 1}
+}
+
+func (s *MemoryReviewStore) List(userID uint) ([]models.PendingJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := []models.PendingJob{}
+	for _, p := range s.pending {
+		if p.UserID == userID {
+			pending = append(pending, p)
+		}
+	}
+	slices.SortFunc(pending, func(a, b models.PendingJob) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+	return pending, nil
+}
+
+func (s *MemoryReviewStore) Get(userID, id uint) (models.PendingJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.pending[id]
+	if !ok || p.UserID != userID {
+		return models.PendingJob{}, ErrNotFound
+	}
+	return p, nil
+}
+
+func (s *MemoryReviewStore) Create(p *models.PendingJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p.ID = s.nextID
+	s.nextID++
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = time.Now()
+	}
+	s.pending[p.ID] = *p
+	return nil
+}
+
+func (s *MemoryReviewStore) FindBySourceMessageID(userID uint, messageID string) (*models.PendingJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range s.pending {
+		if p.UserID == userID && p.SourceMessageID == messageID {
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *MemoryReviewStore) Delete(userID, id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.pending[id]
+	if !ok || p.UserID != userID {
+		return ErrNotFound
+	}
+	delete(s.pending, id)
+	return nil
+}
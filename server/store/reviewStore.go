@@ -0,0 +1,17 @@
+package store
+
+import "github.com/jobTracker/models"
+
+// ReviewStore keeps the pending jobs of the review queue, every method is scoped to the owning user
+type ReviewStore interface {
+	// List returns the user's pending jobs, oldest first
+	List(userID uint) ([]models.PendingJob, error)
+	// Get returns ErrNotFound if the pending job does not exist or belongs to another user
+	Get(userID, id uint) (models.PendingJob, error)
+	// Create queues a pending job for p.UserID
+	Create(p *models.PendingJob) error
+	// FindBySourceMessageID returns the user's pending job parsed from that email, or nil
+	FindBySourceMessageID(userID uint, messageID string) (*models.PendingJob, error)
+	// Delete removes a pending job, ErrNotFound if the user has no such pending job
+	Delete(userID, id uint) error
+}